@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/iamthen0ise/faux/internal/api"
+	"github.com/iamthen0ise/faux/internal/args"
+)
+
+// runLint runs "faux lint": the routes are checked against an OpenAPI spec
+// and every disagreement is printed. It returns the exit code, 1 when there
+// are findings and 2 when the routes or spec cannot be loaded.
+func runLint(arguments []string) int {
+	config := &args.LintConfig{}
+	if err := args.ParseLintInput(arguments, config); err != nil {
+		log.Print(err)
+		return 2
+	}
+
+	router := api.NewRouter()
+	// Tokens are never verified while linting, so routes may rely on server
+	// JWT keys that lint is not given.
+	router.JWTKeys = true
+	info, err := os.Stat(config.Routes)
+	if err != nil {
+		log.Printf("Failed to load routes: %v", err)
+		return 2
+	}
+	if info.IsDir() {
+		err = router.LoadRoutesFromDir(config.Routes)
+	} else {
+		err = router.LoadRoutesFromFiles([]string{config.Routes})
+	}
+	if err != nil {
+		log.Printf("Failed to load routes: %v", err)
+		return 2
+	}
+
+	// The spec is a file or URL here, never an inline document.
+	if !strings.HasPrefix(config.Spec, "http://") && !strings.HasPrefix(config.Spec, "https://") {
+		if _, err := os.Stat(config.Spec); err != nil {
+			log.Printf("Failed to load OpenAPI spec: %v", err)
+			return 2
+		}
+	}
+	spec, err := api.ParseOpenAPISchema(config.Spec)
+	if err != nil {
+		log.Printf("Failed to load OpenAPI spec: %v", err)
+		return 2
+	}
+
+	findings := api.LintRoutes(router.RouteList(), spec)
+	if config.JSON {
+		if findings == nil {
+			findings = []api.LintFinding{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(findings)
+	} else {
+		for _, finding := range findings {
+			fmt.Println(finding)
+		}
+		if len(findings) > 0 {
+			fmt.Printf("%d finding(s)\n", len(findings))
+		}
+	}
+
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
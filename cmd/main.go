@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/iamthen0ise/faux/internal/api"
@@ -63,15 +66,25 @@ RUN WITH --quiet-start TO HIDE THIS^^^
 	statusCode := rand.Intn(301) + 200 // random integer between 200 and 500
 
 	// Set example headers and body
-	examlePayload := `{"response_headers":{"Content-Type":"application/json"},"response_body":"{\"message\": \"Hello from Faux\"}"}`
+	examlePayload := `{"response_headers":{"Content-Type":"application/json"},"body_raw":"{\"message\": \"Hello from Faux\"}"}`
 
 	fmt.Printf("\nExample curl command:\n")
 	fmt.Printf("curl -X %s -d '%s' http://localhost:%d/status/%d\n", randomMethod, examlePayload, port, statusCode)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		runRecord(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
+	}
+
 	appConfig := &args.AppConfig{}
-	args.ParseInput(appConfig)
+	if err := args.ParseInput(appConfig); err != nil {
+		log.Fatalf("Failed to read the configuration: %v", err)
+	}
 
 	if !appConfig.QuietStart && terminalSizeOK() {
 		printWelcomeMessage(appConfig.Port)
@@ -81,9 +94,46 @@ func main() {
 	logger := applogger.NewLogger("[{{.Time}}] {{.Method}} {{.StatusCode}} {{.Path}} {{.ResponseTime}}\n", appConfig.Colorize)
 
 	authMiddleware := &api.AuthMiddleware{Token: appConfig.AuthToken}
+	if len(appConfig.JWTSecrets) > 0 || appConfig.JWTJWKS != "" {
+		authMiddleware.JWT = &api.JWTAuth{
+			Secrets:  appConfig.JWTSecrets,
+			JWKS:     appConfig.JWTJWKS,
+			Issuer:   appConfig.JWTIssuer,
+			Audience: appConfig.JWTAudience,
+		}
+		if err := authMiddleware.JWT.LoadKeys(); err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+	}
 	router := api.NewRouter()
+	router.Journal = api.NewJournal(appConfig.JournalSize)
+	router.PlainNotFound = appConfig.PlainNotFound
+	router.JWTKeys = authMiddleware.JWT != nil
+	router.TokenAuth = authMiddleware.Token != ""
+	switch appConfig.OpenAPIValidation {
+	case api.ValidationEnforce, api.ValidationLog, api.ValidationOff:
+		router.OpenAPIValidation = appConfig.OpenAPIValidation
+	default:
+		log.Fatalf("Unknown OpenAPI validation mode %q, want enforce, log or off", appConfig.OpenAPIValidation)
+	}
+	router.Proxy = &api.ProxyConfig{
+		Target:      appConfig.ProxyTo,
+		RewriteHost: appConfig.ProxyRewriteHost,
+		Headers:     appConfig.ProxyHeaders,
+		Timeout:     time.Duration(appConfig.ProxyTimeout) * time.Millisecond,
+	}
+	if err := router.Proxy.Validate(); err != nil {
+		log.Fatalf("Invalid proxy target: %v", err)
+	}
 	authMiddleware.Next = router
 
+	// Specs are loaded first, so routes files override their operations.
+	if len(appConfig.OpenAPI) > 0 {
+		if err := router.LoadRoutesFromOpenAPISpecs(appConfig.OpenAPI); err != nil {
+			log.Fatalf("Failed to load OpenAPI specs: %v", err)
+		}
+	}
+
 	if appConfig.RoutesPath != "" {
 		fileInfo, err := os.Stat(appConfig.RoutesPath)
 		if err != nil {
@@ -103,28 +153,44 @@ func main() {
 				log.Fatalf("Failed to load routes: %v", err)
 			}
 		}
+
+		// Reload routes whenever the file or directory changes.
+		go api.WatchRoutes(router, appConfig.RoutesPath)
 	}
 
 	http.HandleFunc("/openapi", router.OpenAPIHandler)
+	http.HandleFunc("/openapi.yaml", router.OpenAPIHandler)
 
 	http.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		r, entry := api.StartEntry(r)
 		rec := statusRecorder{ResponseWriter: w}
-		router.ServeHTTP(&rec, r)
+		authMiddleware.ServeHTTP(&rec, r)
 
 		duration := time.Since(start)
 		logger.LogRequest(r, rec.status, duration)
+
+		// Keep the admin API's own traffic out of the journal.
+		if !strings.HasPrefix(r.URL.Path, api.AdminPrefix) {
+			entry.Status = rec.status
+			entry.Duration = duration
+			entry.ResponseHeaders = w.Header().Clone()
+			entry.ResponseBody = string(rec.body)
+			router.Journal.Add(entry)
+		}
 	}))
 
 	// Start the HTTP server.
 	log.Fatal(http.ListenAndServe(appConfig.Host+":"+fmt.Sprint(appConfig.Port), nil))
 }
 
-// statusRecorder is an HTTP ResponseWriter that captures the status code written to it.
+// statusRecorder is an HTTP ResponseWriter that captures the status code
+// written to it and the start of the body for the journal.
 type statusRecorder struct {
 	http.ResponseWriter
 	status int
+	body   []byte
 }
 
 // WriteHeader captures the status code written.
@@ -132,3 +198,25 @@ func (rec *statusRecorder) WriteHeader(code int) {
 	rec.status = code
 	rec.ResponseWriter.WriteHeader(code)
 }
+
+// Hijack hands the connection over to WebSocket endpoints, which answer
+// with 101 Switching Protocols.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rec.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(rec.ResponseWriter).Hijack()
+}
+
+// Write records an implicit 200 when the handler writes without calling
+// WriteHeader first.
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if room := api.MaxJournalResponseBody - len(rec.body); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		rec.body = append(rec.body, b[:room]...)
+	}
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/iamthen0ise/faux/internal/api"
+	"github.com/iamthen0ise/faux/internal/applogger"
+	"github.com/iamthen0ise/faux/internal/args"
+)
+
+// runRecord runs "faux record": requests are forwarded to the target and each
+// new exchange is saved as a routes file, which later runs replay.
+func runRecord(arguments []string) {
+	config := &args.RecordConfig{}
+	if err := args.ParseRecordInput(arguments, config); err != nil {
+		log.Fatal(err)
+	}
+
+	logger := applogger.NewLogger("[{{.Time}}] {{.Method}} {{.StatusCode}} {{.Path}} {{.ResponseTime}}\n", true)
+
+	router := api.NewRouter()
+	router.Proxy = &api.ProxyConfig{
+		RewriteHost: true,
+		Timeout:     time.Duration(config.Timeout) * time.Millisecond,
+	}
+	recorder, err := api.NewRecorder(router, config.Target, config.Out)
+	if err != nil {
+		log.Fatalf("Failed to start recording: %v", err)
+	}
+	recorder.SetReplayOnly(config.ReplayOnly)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := statusRecorder{ResponseWriter: w}
+		recorder.ServeHTTP(&rec, r)
+		logger.LogRequest(r, rec.status, time.Since(start))
+	})
+
+	if recorder.ReplayOnly() {
+		log.Printf("Replaying routes from %s", config.Out)
+	} else {
+		log.Printf("Recording %s into %s", config.Target, config.Out)
+	}
+	log.Fatal(http.ListenAndServe(config.Host+":"+fmt.Sprint(config.Port), handler))
+}
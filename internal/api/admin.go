@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// AdminPrefix is the path prefix reserved for Faux's own admin API. Routes
+// under it are never matched against the configured routes.
+const AdminPrefix = "/__faux/"
+
+func (r *Router) serveAdmin(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	switch {
+	case path == AdminPrefix+"routes" || strings.HasPrefix(path, AdminPrefix+"routes/"):
+		r.serveRoutes(w, req)
+	case path == AdminPrefix+"requests":
+		r.serveRequests(w, req)
+	case path == AdminPrefix+"verify":
+		r.serveVerify(w, req)
+	case path == AdminPrefix+"scenarios" || strings.HasPrefix(path, AdminPrefix+"scenarios/"):
+		r.serveScenarios(w, req)
+	case path == AdminPrefix+"responses/reset":
+		if req.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		r.ResetResponses()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	// The status line is already out, so a failed write cannot be reported.
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+}
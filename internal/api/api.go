@@ -1,27 +1,80 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 
 	"github.com/iamthen0ise/faux/internal/throttling"
 )
 
 type Route struct {
+	// ID is assigned when the route is added unless one is given, and is
+	// used by the admin API to refer to the route.
+	ID              string            `json:"id,omitempty"`
 	Path            string            `json:"path"`
 	Method          string            `json:"method"`
 	StatusCode      int               `json:"status_code"`
 	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
 	ResponseBody    interface{}       `json:"response_body,omitempty"`
+	BodyRaw         string            `json:"body_raw,omitempty"`
+	BodyBase64      string            `json:"body_base64,omitempty"`
+	BodyJSON        interface{}       `json:"body_json,omitempty"`
+	// BodyFile is streamed from disk. A relative path is resolved against the
+	// directory of the routes file that defines the route.
+	BodyFile string `json:"body_file,omitempty"`
+	// BodyTemplate and HeaderTemplates are rendered with text/template on
+	// every request and win over the static body and headers.
+	BodyTemplate    string            `json:"body_template,omitempty"`
+	HeaderTemplates map[string]string `json:"header_templates,omitempty"`
 	Lambda          int               `json:"-"`
 	AuthRequired    bool              `json:"auth_required,omitempty"`
-	ThrottlingLow   int               `json:"throttling_low,omitempty"`
-	ThrottlingHigh  int               `json:"throttling_hi,omitempty"`
-	RateLimitPerMin float32           `json:"rate_limit_per_min,omitempty"`
+	// Auth lists the credentials the route accepts in place of the server's
+	// token. A route with Auth always requires one of them.
+	Auth            *RouteAuth `json:"auth,omitempty"`
+	ThrottlingLow   int        `json:"throttling_low,omitempty"`
+	ThrottlingHigh  int        `json:"throttling_hi,omitempty"`
+	RateLimitPerMin float32    `json:"rate_limit_per_min,omitempty"`
+	// Overridable lets a request override the configured response the same
+	// way it would on a magic route. Overrides are merged on top of it.
+	Overridable bool `json:"overridable,omitempty"`
+	// Responses, when set, are served one per call in the order given by
+	// ResponseMode instead of the route's own status, headers and body.
+	Responses    []RouteResponse `json:"responses,omitempty"`
+	ResponseMode string          `json:"response_mode,omitempty"`
+	// ProxyTo forwards matching requests to this upstream base URL instead of
+	// answering them. Proxy does the same with the router's global upstream.
+	ProxyTo string `json:"proxy_to,omitempty"`
+	Proxy   bool   `json:"proxy,omitempty"`
+	// Match holds extra conditions on the query, headers and body. Routes
+	// sharing a method and path are tried by descending Priority.
+	Match    *RequestMatch `json:"match,omitempty"`
+	Priority int           `json:"priority,omitempty"`
+	// Scenario names a state machine shared by routes. A route with a
+	// RequiredState only matches while the scenario is in that state, and a
+	// route with a NewState moves the scenario there when it matches.
+	Scenario      string `json:"scenario,omitempty"`
+	RequiredState string `json:"required_state,omitempty"`
+	NewState      string `json:"new_state,omitempty"`
+
+	bodyTemplate    *template.Template
+	headerTemplates map[string]*template.Template
+	// openapi is set on routes mapped from an OpenAPI operation.
+	openapi *openAPIRoute
+	// websocket is set on routes mapped from an AsyncAPI WebSocket channel,
+	// and answers WebSocket handshakes.
+	websocket *webSocketMock
 }
 
 const (
@@ -33,18 +86,61 @@ var (
 )
 
 type Router struct {
+	// Routes is keyed by Route.key and guarded by mu.
 	Routes map[string]*Route
+	mu     sync.RWMutex
+	// dynamic holds the IDs of routes added through the admin API, and
+	// stashed the loaded routes they replaced, so both can be reset.
+	dynamic map[string]bool
+	stashed []*Route
+
+	// Proxy, when set, forwards unmatched requests and routes marked for
+	// proxying to an upstream server.
+	Proxy *ProxyConfig
+
+	// PlainNotFound turns off the list of closest routes in 404 responses.
+	PlainNotFound bool
+
+	// OpenAPIValidation is how requests to routes mapped from an OpenAPI
+	// spec are checked against it: ValidationEnforce, ValidationLog or
+	// ValidationOff. Empty means off.
+	OpenAPIValidation string
+
+	// JWTKeys is set when the server has JWT keys of its own. Without them,
+	// a route whose JWT auth names no keys could never let a request in, so
+	// it is refused when loaded.
+	JWTKeys bool
+
+	// TokenAuth is set when the server has a token, so routes with
+	// AuthRequired are protected by it.
+	TokenAuth bool
+
+	// Journal records requests for the admin API. Requests are only recorded
+	// when the server adds them; see StartEntry.
+	Journal *Journal
+
+	// stateMu guards the per-route call counters and scenario states.
+	stateMu   sync.Mutex
+	calls     map[string]int
+	scenarios map[string]string
 }
 
 func NewRouter() *Router {
 	return &Router{
-		Routes: make(map[string]*Route),
+		Routes:    make(map[string]*Route),
+		dynamic:   make(map[string]bool),
+		Journal:   NewJournal(DefaultJournalSize),
+		calls:     make(map[string]int),
+		scenarios: make(map[string]string),
 	}
 }
 
 type MagicRequest struct {
 	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
 	ResponseBody    interface{}       `json:"response_body,omitempty"`
+	BodyRaw         string            `json:"body_raw,omitempty"`
+	BodyBase64      string            `json:"body_base64,omitempty"`
+	BodyJSON        interface{}       `json:"body_json,omitempty"`
 	Lambda          int               `json:"-"`
 	AuthRequired    bool              `json:"auth_required,omitempty"`
 	ThrottlingLow   int               `json:"throttling_low,omitempty"`
@@ -87,8 +183,17 @@ func (r *Router) parseRequestIntoMagicReq(req *http.Request, magicReq *MagicRequ
 					}
 				}
 			} else {
-				if k == "response_body" {
+				switch k {
+				case "response_body":
 					magicReq.ResponseBody = v[0]
+				case "body_raw":
+					magicReq.BodyRaw = v[0]
+				case "body_base64":
+					magicReq.BodyBase64 = v[0]
+				case "body_json":
+					if err := json.Unmarshal([]byte(v[0]), &magicReq.BodyJSON); err != nil {
+						return errors.New("Invalid body_json")
+					}
 				}
 			}
 		}
@@ -119,48 +224,285 @@ func ParseDotNotation(m url.Values) map[string]interface{} {
 	return result
 }
 
+// routeKey identifies a route in Router.Routes by its method and path.
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// key identifies the route in Router.Routes. Routes that share a method and
+// path but have different match conditions are kept apart.
+func (route *Route) key() string {
+	key := routeKey(route.Method, route.Path)
+	if route.Match != nil {
+		key += " " + jsonString(route.Match)
+	}
+	if route.Scenario != "" {
+		key += " " + route.Scenario + ":" + route.RequiredState
+	}
+	return key
+}
+
+// AddRoute adds the route, replacing any route with the same method, path
+// and conditions. A route without an ID takes over the ID of the one it
+// replaces, or gets a new one.
 func (r *Router) AddRoute(route *Route) {
-	r.Routes[route.Path] = route
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addRoute(route)
+}
+
+func (r *Router) addRoute(route *Route) *Route {
+	key := route.key()
+	replaced := r.Routes[key]
+	if route.ID == "" {
+		if replaced != nil {
+			route.ID = replaced.ID
+		} else {
+			route.ID = newUUID()
+		}
+	}
+	r.Routes[key] = route
+	return replaced
+}
+
+// findRoute returns the route for the request along with any values captured
+// from its pattern. Of the routes whose method, path and match conditions fit,
+// the one with the highest priority wins, then the most specific path, then
+// the one with the most conditions. When the path matches only routes for
+// other methods, those methods are returned instead; a route for the
+// request's method whose conditions fail still makes the method allowed.
+func (r *Router) findRoute(req *http.Request) (*Route, map[string]string, []string) {
+	// The body is read before the lock is taken, so a slow upload cannot hold
+	// up changes to the routes.
+	var body []byte
+	if r.matchesBodies() {
+		body, _ = readBody(req)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var (
+		best       *Route
+		bestParams map[string]string
+		allowed    []string
+		// methodFits is set once a route has the request's method, so the
+		// request is a near miss rather than a disallowed method.
+		methodFits bool
+	)
+	for _, route := range r.Routes {
+		params, ok := matchPath(route.Path, req.URL.Path)
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(route.Method, req.Method) {
+			allowed = appendUnique(allowed, strings.ToUpper(route.Method))
+			continue
+		}
+		methodFits = true
+		if best != nil && !route.outranks(best) {
+			continue
+		}
+		if !r.inRequiredState(route) {
+			continue
+		}
+		if route.Match != nil {
+			if len(route.Match.check(req.URL.Query(), req.Header, body)) > 0 {
+				continue
+			}
+		}
+		best, bestParams = route, params
+	}
+
+	if best != nil {
+		return best, bestParams, nil
+	}
+	if methodFits {
+		return nil, nil, nil
+	}
+	sort.Strings(allowed)
+	return nil, nil, allowed
+}
+
+// routeChoice is the route chosen for a request, or the methods allowed on
+// its path when there is none.
+type routeChoice struct {
+	route   *Route
+	params  map[string]string
+	allowed []string
+}
+
+// chooseRoute finds the route for the request and enters its scenario
+// state, looking again if another request moved the scenario on in between.
+func (r *Router) chooseRoute(req *http.Request) routeChoice {
+	for {
+		route, params, allowed := r.findRoute(req)
+		if route == nil || r.enterScenario(route) {
+			return routeChoice{route: route, params: params, allowed: allowed}
+		}
+	}
+}
+
+// withRouteChoice hands the route chosen by a middleware on to the Router,
+// so that it serves the route that was authorized.
+func withRouteChoice(req *http.Request, choice routeChoice) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), routeChoiceKey, choice))
+}
+
+// matchesBodies reports whether any route has conditions on the request body.
+func (r *Router) matchesBodies() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, route := range r.Routes {
+		if route.Match != nil && len(route.Match.Body) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// outranks reports whether the route should be chosen over other when both
+// match a request.
+func (route *Route) outranks(other *Route) bool {
+	if route.Priority != other.Priority {
+		return route.Priority > other.Priority
+	}
+	if cmp := comparePatterns(route.Path, other.Path); cmp != 0 {
+		return cmp > 0
+	}
+	if route.conditionCount() != other.conditionCount() {
+		return route.conditionCount() > other.conditionCount()
+	}
+	return route.key() < other.key()
+}
+
+// conditionCount returns how many conditions beyond method and path the route
+// places on a request.
+func (route *Route) conditionCount() int {
+	count := route.Match.count()
+	if route.RequiredState != "" {
+		count++
+	}
+	return count
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// Check for the specific /openapi route
-	if req.URL.Path == "/openapi" {
+	// Check for the specific /openapi routes
+	if req.URL.Path == "/openapi" || req.URL.Path == "/openapi.yaml" {
 		r.OpenAPIHandler(w, req)
 		return
 	}
 
-	route, ok := r.Routes[req.URL.Path]
-	if !ok && !strings.HasPrefix(req.URL.Path, "/status/") {
-		http.NotFound(w, req)
+	if strings.HasPrefix(req.URL.Path, AdminPrefix) {
+		r.serveAdmin(w, req)
 		return
 	}
 
-	var magicReq MagicRequest
-
-	if ok && route.Method == req.Method {
+	choice, ok := req.Context().Value(routeChoiceKey).(routeChoice)
+	if !ok {
+		choice = r.chooseRoute(req)
+	}
+	route, params, allowed := choice.route, choice.params, choice.allowed
+	switch {
+	case route != nil:
+		noteRoute(req, route)
+		req = withPathParams(req, params)
 		throttlingMiddleware := throttling.ThrottlingMiddleware(route.ThrottlingLow, route.ThrottlingHigh)
 		rateLimitMiddleware := throttling.RateLimitMiddleware(route.RateLimitPerMin)
-		routeHandler := r.handleDefinedRoute(route, &magicReq)
+		routeHandler := r.handleDefinedRoute(route)
+		if target := r.proxyTarget(route); target != "" {
+			routeHandler = r.proxyHandler(target)
+		}
 		handler := throttlingMiddleware(rateLimitMiddleware(routeHandler))
 		handler.ServeHTTP(w, req)
-	} else {
+	case len(allowed) > 0 && r.forwards():
+		r.proxyHandler(r.Proxy.Target).ServeHTTP(w, req)
+	case len(allowed) > 0:
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	case strings.HasPrefix(req.URL.Path, "/status/"):
+		var magicReq MagicRequest
 		r.handleMagicRoute(w, req, &magicReq)
+	case r.forwards():
+		r.proxyHandler(r.Proxy.Target).ServeHTTP(w, req)
+	default:
+		r.notFound(w, req)
 	}
 }
 
-func (r *Router) handleDefinedRoute(route *Route, magicReq *MagicRequest) http.Handler {
+func (r *Router) handleDefinedRoute(route *Route) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if req.Header.Get("Content-Type") == "application/json" {
-			if err := json.NewDecoder(req.Body).Decode(magicReq); err != nil {
-				http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		if !r.validateOpenAPIRequest(w, req, route) {
+			return
+		}
+		if route.websocket != nil && isWebSocketUpgrade(req) {
+			route.websocket.serve(w, req)
+			return
+		}
+
+		statusCode := route.StatusCode
+		body := route.body()
+		headers := route.ResponseHeaders
+		if route.openapi != nil {
+			if resp, ok := route.openapi.preferred(req.Header.Get("Prefer")); ok {
+				statusCode, headers, body = resp.StatusCode, resp.ResponseHeaders, resp.body()
+			}
+		}
+		setHeaders(w, headers)
+
+		if len(route.Responses) > 0 {
+			resp := r.nextResponse(route)
+			if resp.StatusCode != 0 {
+				statusCode = resp.StatusCode
+			}
+			setHeaders(w, resp.ResponseHeaders)
+			if respBody := resp.body(); !respBody.isEmpty() {
+				body = respBody
+			}
+		}
+
+		if route.BodyTemplate != "" || len(route.HeaderTemplates) > 0 {
+			data, err := newTemplateData(req)
+			if err != nil {
+				http.Error(w, "Error reading request body", http.StatusBadRequest)
+				return
+			}
+			headers, rendered, err := route.renderTemplates(data)
+			if err != nil {
+				http.Error(w, "Error rendering response template: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			setHeaders(w, headers)
+			if route.BodyTemplate != "" {
+				body = responseBody{Raw: rendered}
+			}
+		}
+
+		if route.Overridable {
+			var magicReq MagicRequest
+			if err := r.parseRequestIntoMagicReq(req, &magicReq); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-			defer req.Body.Close()
+
+			setHeaders(w, magicReq.ResponseHeaders)
+			if override := magicReq.body(); !override.isEmpty() {
+				body = override
+			}
 		}
 
-		setHeaders(w, magicReq.ResponseHeaders)
-		writeResponse(w, route.StatusCode, magicReq.ResponseBody)
+		writeResponse(w, statusCode, body)
 	})
 }
 
@@ -177,7 +519,7 @@ func (r *Router) handleMagicRoute(w http.ResponseWriter, req *http.Request, magi
 	}
 
 	setHeaders(w, magicReq.ResponseHeaders)
-	writeResponse(w, statusCode, magicReq.ResponseBody)
+	writeResponse(w, statusCode, magicReq.body())
 }
 
 func (r *Router) parseMagicRoute(path string) (int, error) {
@@ -193,30 +535,148 @@ func setHeaders(w http.ResponseWriter, headers map[string]string) {
 	}
 }
 
-func writeResponse(w http.ResponseWriter, statusCode int, responseBody interface{}) {
+// writeResponse encodes the body and writes it with the status code. When no
+// Content-Type was set explicitly, the one implied by the body is used.
+func writeResponse(w http.ResponseWriter, statusCode int, body responseBody) {
+	if body.usesFile() {
+		writeFileResponse(w, statusCode, body.File)
+		return
+	}
+
+	data, contentType, err := body.encode()
+	if err != nil {
+		http.Error(w, "Error processing response body", http.StatusInternalServerError)
+		return
+	}
+
+	if contentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
 	w.WriteHeader(statusCode)
 
-	if responseBody != nil {
-		body, err := json.Marshal(responseBody)
-		if err != nil {
-			http.Error(w, "Error processing response body", http.StatusInternalServerError)
-			return
-		}
+	if len(data) > 0 {
+		// The status line is already out, so a failed write cannot be reported.
+		_, _ = w.Write(data)
+	}
+}
 
-		if _, err = w.Write(body); err != nil {
-			http.Error(w, "Error writing response body", http.StatusInternalServerError)
+// validate checks the parts of a route that can only be checked once it has
+// been decoded.
+func (route *Route) validate() error {
+	key := routeKey(route.Method, route.Path)
+	if route.StatusCode == 0 {
+		route.StatusCode = http.StatusOK
+	}
+	if !validStatus(route.StatusCode) {
+		return fmt.Errorf("route %s: status_code %d is not between 100 and 599", key, route.StatusCode)
+	}
+	for i, resp := range route.Responses {
+		// Zero keeps the route's status.
+		if resp.StatusCode != 0 && !validStatus(resp.StatusCode) {
+			return fmt.Errorf("route %s: response %d: status_code %d is not between 100 and 599", key, i, resp.StatusCode)
 		}
 	}
+	if route.ThrottlingLow < 0 || route.ThrottlingHigh < route.ThrottlingLow {
+		return fmt.Errorf("route %s: throttling_hi must be at least throttling_low, and both at least 0", key)
+	}
+	if route.RateLimitPerMin != 0 && route.RateLimitPerMin < 1 {
+		return fmt.Errorf("route %s: rate_limit_per_min must be at least 1", key)
+	}
+	if err := route.body().validate(); err != nil {
+		return fmt.Errorf("route %s: %w", key, err)
+	}
+	for i := range route.Responses {
+		if err := route.Responses[i].body().validate(); err != nil {
+			return fmt.Errorf("route %s: response %d: %w", key, i, err)
+		}
+	}
+	switch route.ResponseMode {
+	case "", ResponseModeSequence, ResponseModeCycle, ResponseModeRandomWeighted:
+	default:
+		return fmt.Errorf("route %s: unknown response_mode %q", key, route.ResponseMode)
+	}
+	if route.ProxyTo != "" {
+		if err := validateProxyURL(route.ProxyTo); err != nil {
+			return fmt.Errorf("route %s: invalid proxy_to: %w", key, err)
+		}
+	}
+	if route.Auth != nil {
+		if err := route.Auth.validate(); err != nil {
+			return fmt.Errorf("route %s: invalid auth: %w", key, err)
+		}
+	}
+	if route.Match != nil {
+		if err := route.Match.validate(); err != nil {
+			return fmt.Errorf("route %s: invalid match: %w", key, err)
+		}
+	}
+	if err := route.compileTemplates(); err != nil {
+		return fmt.Errorf("route %s: invalid template: %w", key, err)
+	}
+	return nil
+}
+
+// validateRoute is validate plus the checks that depend on the router.
+func (r *Router) validateRoute(route *Route) error {
+	if err := route.validate(); err != nil {
+		return err
+	}
+	if route.Auth != nil && route.Auth.JWT != nil && !route.Auth.JWT.hasKeys() && !r.JWTKeys {
+		return fmt.Errorf("route %s: invalid auth: jwt: no secrets or jwks, and the server has no JWT keys", routeKey(route.Method, route.Path))
+	}
+	if route.Proxy && route.ProxyTo == "" && !r.forwards() {
+		log.Printf("Warning: route %s is marked proxy but there is no -proxy-to target; it is served locally", routeKey(route.Method, route.Path))
+	}
+	return nil
+}
+
+// validStatus reports whether code can be written as an HTTP status.
+func validStatus(code int) bool {
+	return code >= 100 && code <= 599
+}
+
+// readBody reads the whole request body and replaces it with a copy, so it
+// can be read again further down the chain.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, err
 }
 
 func (r *Router) LoadRoutesFromJSON(data []byte) error {
+	return r.loadRoutesJSON(data, "JSON input", "", make(map[string]string))
+}
+
+// loadRoutesJSON adds the routes in data to the router. Relative body files
+// are resolved against baseDir. seen maps the key of every route loaded so far
+// in the same batch to its source, so that a route defined twice can be
+// reported.
+func (r *Router) loadRoutesJSON(data []byte, source, baseDir string, seen map[string]string) error {
 	var routes []Route
 	if err := json.Unmarshal(data, &routes); err != nil {
 		return err
 	}
+	return r.addLoadedRoutes(routes, source, baseDir, seen)
+}
 
+// addLoadedRoutes validates routes read from source and adds them, warning
+// about any already in seen.
+func (r *Router) addLoadedRoutes(routes []Route, source, baseDir string, seen map[string]string) error {
 	for _, route := range routes {
 		newRoute := route
+		newRoute.resolveRelativePaths(baseDir)
+		if err := r.validateRoute(&newRoute); err != nil {
+			return err
+		}
+		key := newRoute.key()
+		if first, ok := seen[key]; ok {
+			log.Printf("Warning: route %s in %s duplicates one in %s; the later definition wins", key, source, first)
+		}
+		seen[key] = source
 		r.AddRoute(&newRoute)
 	}
 
@@ -22,7 +22,7 @@ func TestAddRoute(t *testing.T) {
 
 	router.AddRoute(route)
 
-	if _, ok := router.Routes["/test"]; !ok {
+	if _, ok := router.Routes["GET /test"]; !ok {
 		t.Fatalf("Route not added correctly.")
 	}
 }
@@ -30,9 +30,10 @@ func TestAddRoute(t *testing.T) {
 func TestServeHTTP_JsonBody(t *testing.T) {
 	router := NewRouter()
 	route := &Route{
-		Path:       "/test",
-		Method:     "POST",
-		StatusCode: 200,
+		Path:        "/test",
+		Method:      "POST",
+		StatusCode:  200,
+		Overridable: true,
 	}
 
 	router.AddRoute(route)
@@ -62,6 +63,58 @@ func TestServeHTTP_JsonBody(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_DefinedResponse(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:            "/test",
+		Method:          "GET",
+		StatusCode:      200,
+		ResponseHeaders: map[string]string{"Content-Type": "application/json", "X-Mock": "faux"},
+		ResponseBody:    map[string]interface{}{"message": "Hello"},
+	})
+
+	req := httptest.NewRequest("GET", "/test?response_body=ignored", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if xmock := rr.Header().Get("X-Mock"); xmock != "faux" {
+		t.Errorf("X-Mock header does not match: got %v want %v", xmock, "faux")
+	}
+
+	expected := `{"message":"Hello"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
+func TestServeHTTP_OverridableMerge(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:            "/test",
+		Method:          "GET",
+		StatusCode:      200,
+		ResponseHeaders: map[string]string{"Content-Type": "application/json", "X-Mock": "faux"},
+		ResponseBody:    map[string]interface{}{"message": "Hello"},
+		Overridable:     true,
+	})
+
+	req := httptest.NewRequest("GET", "/test?response_headers.X-Mock=override", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if xmock := rr.Header().Get("X-Mock"); xmock != "override" {
+		t.Errorf("X-Mock header does not match: got %v want %v", xmock, "override")
+	}
+	if ctype := rr.Header().Get("Content-Type"); ctype != "application/json" {
+		t.Errorf("Content type header does not match: got %v want %v", ctype, "application/json")
+	}
+
+	expected := `{"message":"Hello"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
+
 func TestServeHTTP_NoArgs(t *testing.T) {
 	router := NewRouter()
 	route := &Route{
@@ -428,10 +481,17 @@ func TestLoadRoutesFromJSON(t *testing.T) {
 	}
 
 	for _, expected := range expectedRoutes {
-		route, ok := router.Routes[expected.Path]
+		route, ok := router.Routes[routeKey(expected.Method, expected.Path)]
 		if !ok {
 			t.Errorf("Route not added: %s", expected.Path)
-		} else if !reflect.DeepEqual(route, &expected) {
+			continue
+		}
+		if route.ID == "" {
+			t.Errorf("Route has no ID: %s", expected.Path)
+		}
+		// IDs are generated, so only the rest of the route is compared.
+		expected.ID = route.ID
+		if !reflect.DeepEqual(route, &expected) {
 			t.Errorf("Route not correctly added: got %+v, want %+v", route, &expected)
 		}
 	}
@@ -571,3 +631,34 @@ func TestRateLimiting(t *testing.T) {
 			status, http.StatusTooManyRequests)
 	}
 }
+
+func TestServeHTTP_MultipleMethods(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/orders", Method: "GET", StatusCode: http.StatusOK})
+	router.AddRoute(&Route{Path: "/orders", Method: "POST", StatusCode: http.StatusCreated})
+
+	if len(router.Routes) != 2 {
+		t.Fatalf("Expected two routes, got %v", len(router.Routes))
+	}
+
+	for method, expected := range map[string]int{"GET": http.StatusOK, "POST": http.StatusCreated} {
+		req := httptest.NewRequest(method, "/orders", http.NoBody)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != expected {
+			t.Errorf("%s: got status %v want %v", method, rr.Code, expected)
+		}
+	}
+
+	req := httptest.NewRequest("DELETE", "/orders", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := rr.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("Allow header does not match: got %v want %v", allow, "GET, POST")
+	}
+}
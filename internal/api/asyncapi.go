@@ -0,0 +1,337 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+)
+
+type asyncAPIDocument struct {
+	AsyncAPI           string                     `json:"asyncapi"`
+	DefaultContentType string                     `json:"defaultContentType"`
+	Servers            map[string]asyncAPIServer  `json:"servers"`
+	Channels           map[string]asyncAPIChannel `json:"channels"`
+}
+
+type asyncAPIServer struct {
+	URL      string `json:"url"`
+	Protocol string `json:"protocol"`
+}
+
+type asyncAPIChannel struct {
+	// Servers limits the channel to some of the document's servers.
+	Servers   []string           `json:"servers"`
+	Bindings  asyncAPIBindings   `json:"bindings"`
+	Subscribe *asyncAPIOperation `json:"subscribe"`
+	Publish   *asyncAPIOperation `json:"publish"`
+}
+
+type asyncAPIBindings struct {
+	HTTP *struct {
+		Method string `json:"method"`
+	} `json:"http"`
+	WS json.RawMessage `json:"ws"`
+}
+
+type asyncAPIOperation struct {
+	Bindings asyncAPIBindings `json:"bindings"`
+	Message  *asyncAPIMessage `json:"message"`
+}
+
+type asyncAPIMessage struct {
+	ContentType string              `json:"contentType"`
+	Payload     *openapi3.SchemaRef `json:"payload"`
+	Examples    []asyncAPIExample   `json:"examples"`
+	OneOf       []*asyncAPIMessage  `json:"oneOf"`
+}
+
+type asyncAPIExample struct {
+	Headers map[string]interface{} `json:"headers"`
+	Payload interface{}            `json:"payload"`
+}
+
+// Channel protocols Faux can mock.
+const (
+	asyncAPIHTTP      = "http"
+	asyncAPIWebSocket = "ws"
+)
+
+// MapAsyncAPIRoutes returns routes for the HTTP and WebSocket channels of an
+// AsyncAPI 2.x document; channels on other protocols are left out. On an HTTP
+// channel, subscribe answers a GET with its message examples, one per call,
+// and publish accepts a POST with a 202. Operation bindings may set other
+// methods. A WebSocket channel sends its subscribe examples to every client
+// that connects and answers plain requests with 426 Upgrade Required.
+// Messages without examples get one generated from their payload schema.
+func MapAsyncAPIRoutes(data []byte) ([]Route, error) {
+	doc, err := parseAsyncAPI(data)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(doc.Channels))
+	for name := range doc.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var routes []Route
+	for _, name := range names {
+		channel := doc.Channels[name]
+		path := name
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+
+		switch doc.protocol(&channel) {
+		case asyncAPIHTTP:
+			if channel.Subscribe != nil {
+				routes = append(routes, doc.httpRoute(path, channel.Subscribe, http.MethodGet))
+			}
+			if channel.Publish != nil {
+				route := Route{
+					Path:       path,
+					Method:     operationMethod(channel.Publish, http.MethodPost),
+					StatusCode: http.StatusAccepted,
+				}
+				if channel.Subscribe == nil || route.Method != operationMethod(channel.Subscribe, http.MethodGet) {
+					routes = append(routes, route)
+				}
+			}
+		case asyncAPIWebSocket:
+			routes = append(routes, doc.webSocketRoute(path, channel.Subscribe))
+		}
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no HTTP or WebSocket channels")
+	}
+	return routes, nil
+}
+
+// parseAsyncAPI reads a JSON or YAML AsyncAPI 2.x document, with its local
+// $refs resolved.
+func parseAsyncAPI(data []byte) (*asyncAPIDocument, error) {
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	resolved, err := resolveLocalRefs(tree, tree, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	resolvedJSON, err := json.Marshal(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc asyncAPIDocument
+	if err := json.Unmarshal(resolvedJSON, &doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.AsyncAPI, "2.") {
+		return nil, fmt.Errorf("unsupported asyncapi version %q", doc.AsyncAPI)
+	}
+	return &doc, nil
+}
+
+// resolveLocalRefs replaces every {"$ref": "#/..."} in node with the part of
+// root it points to. A reference back into itself is cut off with an empty
+// object.
+func resolveLocalRefs(node interface{}, root map[string]interface{}, visiting map[string]bool) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if !strings.HasPrefix(ref, "#/") {
+				return nil, fmt.Errorf("unsupported $ref %q: only references within the document are resolved", ref)
+			}
+			if visiting[ref] {
+				return map[string]interface{}{}, nil
+			}
+			target, err := lookupPointer(root, ref[2:])
+			if err != nil {
+				return nil, err
+			}
+			visiting[ref] = true
+			defer delete(visiting, ref)
+			return resolveLocalRefs(target, root, visiting)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			r, err := resolveLocalRefs(value, root, visiting)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, value := range v {
+			r, err := resolveLocalRefs(value, root, visiting)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	}
+	return node, nil
+}
+
+// lookupPointer follows a JSON pointer, without its leading "#/", from root.
+func lookupPointer(root map[string]interface{}, pointer string) (interface{}, error) {
+	var node interface{} = root
+	for _, token := range strings.Split(pointer, "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("$ref #/%s not found", pointer)
+		}
+		if node, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("$ref #/%s not found", pointer)
+		}
+	}
+	return node, nil
+}
+
+// protocol tells whether a channel is mocked over HTTP or WebSocket, going by
+// its bindings and then by the protocols of its servers. It returns "" for a
+// channel that is neither.
+func (doc *asyncAPIDocument) protocol(channel *asyncAPIChannel) string {
+	for _, bindings := range []*asyncAPIBindings{&channel.Bindings, operationBindings(channel.Subscribe), operationBindings(channel.Publish)} {
+		switch {
+		case bindings == nil:
+		case bindings.WS != nil:
+			return asyncAPIWebSocket
+		case bindings.HTTP != nil:
+			return asyncAPIHTTP
+		}
+	}
+
+	servers := channel.Servers
+	if len(servers) == 0 {
+		for name := range doc.Servers {
+			servers = append(servers, name)
+		}
+		sort.Strings(servers)
+	}
+	for _, name := range servers {
+		switch strings.ToLower(doc.Servers[name].Protocol) {
+		case "http", "https":
+			return asyncAPIHTTP
+		case "ws", "wss":
+			return asyncAPIWebSocket
+		}
+	}
+	return ""
+}
+
+func operationBindings(operation *asyncAPIOperation) *asyncAPIBindings {
+	if operation == nil {
+		return nil
+	}
+	return &operation.Bindings
+}
+
+// operationMethod returns the method set in the operation's HTTP binding, or
+// fallback.
+func operationMethod(operation *asyncAPIOperation, fallback string) string {
+	if binding := operation.Bindings.HTTP; binding != nil && binding.Method != "" {
+		return strings.ToUpper(binding.Method)
+	}
+	return fallback
+}
+
+// httpRoute serves the operation's examples, cycling through them when there
+// are several.
+func (doc *asyncAPIDocument) httpRoute(path string, operation *asyncAPIOperation, method string) Route {
+	route := Route{
+		Path:       path,
+		Method:     operationMethod(operation, method),
+		StatusCode: http.StatusOK,
+	}
+	responses := doc.examples(operation.Message)
+	switch len(responses) {
+	case 0:
+	case 1:
+		route.ResponseHeaders = responses[0].ResponseHeaders
+		route.BodyRaw, route.BodyJSON = responses[0].BodyRaw, responses[0].BodyJSON
+	default:
+		route.Responses = responses
+		route.ResponseMode = ResponseModeCycle
+	}
+	return route
+}
+
+// webSocketRoute answers a WebSocket handshake on the channel by sending the
+// subscribe examples, if there is a subscribe operation.
+func (doc *asyncAPIDocument) webSocketRoute(path string, subscribe *asyncAPIOperation) Route {
+	mock := &webSocketMock{}
+	if subscribe != nil {
+		for _, resp := range doc.examples(subscribe.Message) {
+			if resp.BodyRaw != "" {
+				mock.messages = append(mock.messages, []byte(resp.BodyRaw))
+			} else {
+				mock.messages = append(mock.messages, []byte(jsonString(resp.BodyJSON)))
+			}
+		}
+	}
+	return Route{
+		Path:            path,
+		Method:          http.MethodGet,
+		StatusCode:      http.StatusUpgradeRequired,
+		ResponseHeaders: map[string]string{"Upgrade": "websocket"},
+		BodyRaw:         "This endpoint needs a WebSocket connection.",
+		websocket:       mock,
+	}
+}
+
+// examples returns a response for every example of the message, or of each
+// of its oneOf alternatives. A message without examples gets one generated
+// from its payload schema.
+func (doc *asyncAPIDocument) examples(message *asyncAPIMessage) []RouteResponse {
+	if message == nil {
+		return nil
+	}
+	if len(message.OneOf) > 0 {
+		var responses []RouteResponse
+		for _, alternative := range message.OneOf {
+			responses = append(responses, doc.examples(alternative)...)
+		}
+		return responses
+	}
+
+	contentType := message.ContentType
+	if contentType == "" {
+		contentType = doc.DefaultContentType
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	var responses []RouteResponse
+	for _, example := range message.Examples {
+		if example.Payload == nil {
+			continue
+		}
+		resp := exampleResponse(contentType, example.Payload)
+		resp.ResponseHeaders = map[string]string{"Content-Type": contentType}
+		for name, value := range example.Headers {
+			resp.ResponseHeaders[name] = exampleText(value)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) == 0 {
+		if payload := exampleFromSchema(message.Payload); payload != nil {
+			resp := exampleResponse(contentType, payload)
+			resp.ResponseHeaders = map[string]string{"Content-Type": contentType}
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
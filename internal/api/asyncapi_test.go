@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockAsyncAPI = `
+asyncapi: 2.6.0
+info: {title: Events, version: "1"}
+servers:
+  api: {url: api.example.com, protocol: https}
+  stream: {url: stream.example.com, protocol: wss}
+  broker: {url: broker.example.com, protocol: kafka}
+channels:
+  users/{userId}:
+    servers: [api]
+    subscribe:
+      message: {$ref: '#/components/messages/User'}
+    publish:
+      bindings:
+        http: {type: request, method: PUT}
+      message: {$ref: '#/components/messages/User'}
+  /prices:
+    servers: [stream]
+    subscribe:
+      message:
+        oneOf:
+          - examples:
+              - payload: {symbol: ACME, price: 10}
+              - payload: {symbol: ACME, price: 11}
+          - payload:
+              type: object
+              properties:
+                heartbeat: {type: boolean}
+  /audit:
+    servers: [broker]
+    subscribe:
+      message: {payload: {type: string}}
+components:
+  messages:
+    User:
+      contentType: application/json
+      payload: {$ref: '#/components/schemas/User'}
+      examples:
+        - headers: {X-Version: 2}
+          payload: {id: 1, name: Ada}
+        - payload: {id: 2, name: Grace}
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: integer}
+        name: {type: string}
+        manager: {$ref: '#/components/schemas/User'}
+`
+
+func TestMapAsyncAPIRoutes(t *testing.T) {
+	routes, err := MapAsyncAPIRoutes([]byte(mockAsyncAPI))
+	assert.NoError(t, err)
+
+	var keys []string
+	for _, route := range routes {
+		keys = append(keys, routeKey(route.Method, route.Path))
+	}
+	// The Kafka channel has nothing to mock.
+	assert.ElementsMatch(t, []string{"GET /prices", "GET /users/{userId}", "PUT /users/{userId}"}, keys)
+
+	_, err = MapAsyncAPIRoutes([]byte("asyncapi: 3.0.0\nchannels: {}"))
+	assert.Error(t, err)
+	_, err = MapAsyncAPIRoutes([]byte("asyncapi: 2.6.0\nchannels: {a: {subscribe: {message: {$ref: 'other.yaml#/Msg'}}}}"))
+	assert.Error(t, err)
+}
+
+func TestLoadRoutesFromOpenAPI_AsyncAPIHTTP(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockAsyncAPI))
+
+	// The examples are served in turn.
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/1", http.NoBody))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-Version"))
+	assert.JSONEq(t, `{"id": 1, "name": "Ada"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/1", http.NoBody))
+	assert.JSONEq(t, `{"id": 2, "name": "Grace"}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusAccepted, serveStatus(router, "PUT", "/users/1"))
+	assert.Equal(t, http.StatusUpgradeRequired, serveStatus(router, "GET", "/prices"))
+}
+
+func TestLoadRoutesFromOpenAPI_AsyncAPIWebSocket(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockAsyncAPI))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The key and accept value are the example from RFC 6455.
+	fmt.Fprint(conn, "GET /prices HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	for _, want := range []string{`{"price":10,"symbol":"ACME"}`, `{"price":11,"symbol":"ACME"}`, `{"heartbeat":true}`} {
+		opcode, payload, err := readWebSocketFrame(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, byte(wsText), opcode)
+		assert.JSONEq(t, want, string(payload))
+	}
+
+	// A masked ping from the client is answered with a pong.
+	mask := []byte{1, 2, 3, 4}
+	ping := []byte("hi")
+	frame := []byte{0x80 | wsPing, 0x80 | byte(len(ping))}
+	frame = append(frame, mask...)
+	for i, c := range ping {
+		frame = append(frame, c^mask[i%4])
+	}
+	_, err = conn.Write(frame)
+	assert.NoError(t, err)
+	opcode, payload, err := readWebSocketFrame(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(wsPong), opcode)
+	assert.Equal(t, "hi", string(payload))
+}
@@ -1,33 +1,315 @@
 package api
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// authRealm is the realm named in WWW-Authenticate challenges.
+const authRealm = "faux"
+
+// Default names of the headers credentials are read from.
+const (
+	defaultAuthHeader   = "Authorization"
+	defaultAPIKeyHeader = "X-API-Key"
+)
+
+// RouteAuth lists the credentials a route accepts. A request is let through
+// when it carries any one of them.
+type RouteAuth struct {
+	// Bearer lists tokens sent as "Bearer <token>".
+	Bearer []string `json:"bearer,omitempty"`
+	// Basic lists users and passwords sent with HTTP basic auth.
+	Basic []BasicCredential `json:"basic,omitempty"`
+	// APIKeys lists keys sent in a header or query parameter.
+	APIKeys []APIKey `json:"api_keys,omitempty"`
+	// JWT accepts bearer tokens that are JWTs signed with its keys, or with
+	// the server's when it has none of its own.
+	JWT *JWTAuth `json:"jwt,omitempty"`
+	// Header is where bearer tokens and basic credentials are read from,
+	// Authorization by default.
+	Header string `json:"header,omitempty"`
+}
+
+type BasicCredential struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// APIKey is a key sent in the Query parameter when one is named, and in the
+// Header otherwise, X-API-Key by default.
+type APIKey struct {
+	Key    string `json:"key"`
+	Header string `json:"header,omitempty"`
+	Query  string `json:"query,omitempty"`
+}
+
 type AuthMiddleware struct {
+	// Token protects the admin API and routes with auth_required. It is
+	// accepted in the Authorization header either bare or as a bearer token.
 	Token string
-	Next  http.Handler
+	// JWT, when set, lets routes with auth_required in with a valid JWT, and
+	// holds the keys for routes whose JWT auth has none.
+	JWT  *JWTAuth
+	Next http.Handler
 }
 
 func (a *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// if no token provided, skip auth check
-	if a.Token == "" {
+	// The admin API is always protected once a token is set.
+	if strings.HasPrefix(r.URL.Path, AdminPrefix) {
+		if a.Token != "" && !a.tokenMatches(r) {
+			a.unauthorized(w, nil)
+			return
+		}
 		a.Next.ServeHTTP(w, r)
 		return
 	}
 
-	// Assume we're inside the Router and can access its routes.
-	route, ok := a.Next.(*Router).Routes[r.URL.Path]
-	if !ok || !route.AuthRequired {
-		a.Next.ServeHTTP(w, r)
-		return
+	// Assume we're inside the Router and can access its routes. The route is
+	// chosen here and handed on, so the Router serves the route that was
+	// authorized.
+	router := a.Next.(*Router)
+	for {
+		route, params, allowed := router.findRoute(r)
+		authorized, ok := a.authorize(w, r, route)
+		if !ok {
+			return
+		}
+		if route == nil || router.enterScenario(route) {
+			a.Next.ServeHTTP(w, withRouteChoice(authorized, routeChoice{route: route, params: params, allowed: allowed}))
+			return
+		}
 	}
+}
 
-	headerToken := r.Header.Get("Authorization")
-	if headerToken != a.Token {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+// authorize checks the request's credentials for the route. It answers the
+// request and reports false when they fall short, and otherwise returns the
+// request with the claims of any JWT it carried.
+func (a *AuthMiddleware) authorize(w http.ResponseWriter, r *http.Request, route *Route) (*http.Request, bool) {
+	switch {
+	case route == nil:
+	case route.Auth != nil:
+		if route.Auth.allows(r) {
+			break
+		}
+		if route.Auth.JWT == nil {
+			a.unauthorized(w, route.Auth)
+			return nil, false
+		}
+		token, ok := bearerToken(r.Header.Get(route.Auth.header()))
+		if !ok {
+			a.unauthorized(w, route.Auth)
+			return nil, false
+		}
+		jwt := route.Auth.JWT.withKeys(a.JWT)
+		claims, err := jwt.verify(token, time.Now())
+		if err != nil {
+			a.rejectJWT(w, jwt, err)
+			return nil, false
+		}
+		r = withJWTClaims(r, claims)
+	case route.AuthRequired && a.JWT != nil:
+		if a.Token != "" && a.tokenMatches(r) {
+			break
+		}
+		token, ok := bearerToken(r.Header.Get(defaultAuthHeader))
+		if !ok {
+			a.unauthorized(w, nil)
+			return nil, false
+		}
+		claims, err := a.JWT.verify(token, time.Now())
+		if err != nil {
+			a.rejectJWT(w, a.JWT, err)
+			return nil, false
+		}
+		r = withJWTClaims(r, claims)
+	case route.AuthRequired && a.Token != "":
+		if !a.tokenMatches(r) {
+			a.unauthorized(w, nil)
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+// tokenMatches checks the global token, bare or as a bearer token.
+func (a *AuthMiddleware) tokenMatches(r *http.Request) bool {
+	header := r.Header.Get(defaultAuthHeader)
+	if token, ok := bearerToken(header); ok && secretEqual(token, a.Token) {
+		return true
+	}
+	return secretEqual(header, a.Token)
+}
+
+// unauthorized answers 401 with a challenge for each scheme the route takes,
+// or for a bearer token when it only takes the global token.
+func (a *AuthMiddleware) unauthorized(w http.ResponseWriter, auth *RouteAuth) {
+	challenges := []string{fmt.Sprintf("Bearer realm=%q", authRealm)}
+	if auth != nil {
+		challenges = auth.challenges()
+	}
+	for _, challenge := range challenges {
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// rejectJWT answers as RFC 6750 says: 401 with invalid_token for a token
+// that does not verify, and 403 with insufficient_scope for one that lacks
+// the scopes or claims the route requires.
+func (a *AuthMiddleware) rejectJWT(w http.ResponseWriter, jwt *JWTAuth, err *jwtError) {
+	challenge := fmt.Sprintf("Bearer realm=%q, error=%q, error_description=%q", authRealm, err.code, err.description)
+	status := http.StatusUnauthorized
+	if err.code == jwtInsufficientScope {
+		status = http.StatusForbidden
+		if len(jwt.Scopes) > 0 {
+			challenge += fmt.Sprintf(", scope=%q", strings.Join(jwt.Scopes, " "))
+		}
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// validate checks that there is at least one credential and none is empty,
+// and loads the JWT keys.
+func (auth *RouteAuth) validate() error {
+	if len(auth.Bearer) == 0 && len(auth.Basic) == 0 && len(auth.APIKeys) == 0 && auth.JWT == nil {
+		return fmt.Errorf("no credentials")
+	}
+	for _, token := range auth.Bearer {
+		if token == "" {
+			return fmt.Errorf("empty bearer token")
+		}
 	}
+	for _, credential := range auth.Basic {
+		if credential.User == "" {
+			return fmt.Errorf("basic credential without a user")
+		}
+	}
+	for _, key := range auth.APIKeys {
+		if key.Key == "" {
+			return fmt.Errorf("empty api key")
+		}
+	}
+	if auth.JWT != nil {
+		if auth.JWT.Leeway < 0 {
+			return fmt.Errorf("jwt: negative leeway")
+		}
+		if err := auth.JWT.LoadKeys(); err != nil {
+			return fmt.Errorf("jwt: %w", err)
+		}
+	}
+	return nil
+}
+
+// allows reports whether the request carries any of the credentials.
+func (auth *RouteAuth) allows(r *http.Request) bool {
+	header := r.Header.Get(auth.header())
+	if token, ok := bearerToken(header); ok {
+		for _, accepted := range auth.Bearer {
+			if secretEqual(token, accepted) {
+				return true
+			}
+		}
+	}
+	if user, password, ok := basicCredentials(header); ok {
+		for _, accepted := range auth.Basic {
+			// Both are compared, so a wrong user takes as long as a wrong
+			// password.
+			userOK := secretEqual(user, accepted.User)
+			passwordOK := secretEqual(password, accepted.Password)
+			if userOK && passwordOK {
+				return true
+			}
+		}
+	}
+	for _, key := range auth.APIKeys {
+		var sent string
+		if key.Query != "" {
+			sent = r.URL.Query().Get(key.Query)
+		} else {
+			sent = r.Header.Get(key.header())
+		}
+		if sent != "" && secretEqual(sent, key.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// challenges returns a WWW-Authenticate value for each scheme the route
+// takes. API keys have no registered scheme, so theirs names where the key
+// goes.
+func (auth *RouteAuth) challenges() []string {
+	var challenges []string
+	if len(auth.Bearer) > 0 || auth.JWT != nil {
+		challenges = append(challenges, fmt.Sprintf("Bearer realm=%q", authRealm))
+	}
+	if len(auth.Basic) > 0 {
+		challenges = append(challenges, fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", authRealm))
+	}
+	seen := make(map[string]bool)
+	for _, key := range auth.APIKeys {
+		in, name := "header", key.header()
+		if key.Query != "" {
+			in, name = "query", key.Query
+		}
+		challenge := fmt.Sprintf("ApiKey realm=%q, in=%q, name=%q", authRealm, in, name)
+		if !seen[challenge] {
+			seen[challenge] = true
+			challenges = append(challenges, challenge)
+		}
+	}
+	return challenges
+}
+
+func (auth *RouteAuth) header() string {
+	if auth.Header != "" {
+		return auth.Header
+	}
+	return defaultAuthHeader
+}
+
+func (key *APIKey) header() string {
+	if key.Header != "" {
+		return key.Header
+	}
+	return defaultAPIKeyHeader
+}
+
+// bearerToken returns the token of a "Bearer <token>" header value. The
+// scheme is case-insensitive.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// basicCredentials decodes a "Basic <base64 user:password>" header value.
+func basicCredentials(header string) (string, string, bool) {
+	scheme, encoded, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Basic") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
 
-	a.Next.ServeHTTP(w, r)
+// secretEqual compares in constant time. Hashing first keeps the time from
+// depending on the secret's length as well.
+func secretEqual(sent, secret string) bool {
+	a := sha256.Sum256([]byte(sent))
+	b := sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
 }
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthMiddleware_RouteAuth(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:       "/private",
+		Method:     "GET",
+		StatusCode: http.StatusOK,
+		Auth: &RouteAuth{
+			Bearer: []string{"t0ken"},
+			Basic:  []BasicCredential{{User: "ada", Password: "lovelace"}},
+			APIKeys: []APIKey{
+				{Key: "k3y"},
+				{Key: "c0de", Header: "X-Client-Code"},
+				{Key: "q", Query: "api_key"},
+			},
+		},
+	})
+	router.AddRoute(&Route{
+		Path:       "/proxied",
+		Method:     "GET",
+		StatusCode: http.StatusOK,
+		Auth:       &RouteAuth{Bearer: []string{"t0ken"}, Header: "X-Forwarded-Authorization"},
+	})
+	router.AddRoute(&Route{Path: "/global", Method: "GET", StatusCode: http.StatusOK, AuthRequired: true})
+	// Routes with their own credentials are protected even without a token.
+	middleware := &AuthMiddleware{Next: router}
+
+	testCases := []struct {
+		desc       string
+		path       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{desc: "no credentials", path: "/private", wantStatus: http.StatusUnauthorized},
+		{desc: "bearer", path: "/private", header: "Authorization", value: "Bearer t0ken", wantStatus: http.StatusOK},
+		{desc: "bearer scheme is case-insensitive", path: "/private", header: "Authorization", value: "bearer t0ken", wantStatus: http.StatusOK},
+		{desc: "bare token", path: "/private", header: "Authorization", value: "t0ken", wantStatus: http.StatusUnauthorized},
+		{desc: "wrong bearer", path: "/private", header: "Authorization", value: "Bearer t0ke", wantStatus: http.StatusUnauthorized},
+		{desc: "basic", path: "/private", header: "Authorization", value: "Basic YWRhOmxvdmVsYWNl", wantStatus: http.StatusOK},
+		{desc: "wrong basic password", path: "/private", header: "Authorization", value: "Basic YWRhOmJ5cm9u", wantStatus: http.StatusUnauthorized},
+		{desc: "api key in default header", path: "/private", header: "X-API-Key", value: "k3y", wantStatus: http.StatusOK},
+		{desc: "api key in custom header", path: "/private", header: "X-Client-Code", value: "c0de", wantStatus: http.StatusOK},
+		{desc: "api key in wrong header", path: "/private", header: "X-API-Key", value: "c0de", wantStatus: http.StatusUnauthorized},
+		{desc: "api key in query", path: "/private?api_key=q", wantStatus: http.StatusOK},
+		{desc: "custom bearer header", path: "/proxied", header: "X-Forwarded-Authorization", value: "Bearer t0ken", wantStatus: http.StatusOK},
+		{desc: "bearer in Authorization instead of custom header", path: "/proxied", header: "Authorization", value: "Bearer t0ken", wantStatus: http.StatusUnauthorized},
+		{desc: "auth_required without a token", path: "/global", wantStatus: http.StatusOK},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tC.path, http.NoBody)
+			if tC.header != "" {
+				req.Header.Set(tC.header, tC.value)
+			}
+			rr := httptest.NewRecorder()
+			middleware.ServeHTTP(rr, req)
+			if status := rr.Code; status != tC.wantStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, tC.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_Challenge(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:       "/private",
+		Method:     "GET",
+		StatusCode: http.StatusOK,
+		Auth: &RouteAuth{
+			Basic:   []BasicCredential{{User: "ada", Password: "lovelace"}},
+			APIKeys: []APIKey{{Key: "q", Query: "api_key"}},
+		},
+	})
+	router.AddRoute(&Route{Path: "/global", Method: "GET", StatusCode: http.StatusOK, AuthRequired: true})
+	middleware := &AuthMiddleware{Token: "mytoken", Next: router}
+
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, httptest.NewRequest("GET", "/private", http.NoBody))
+	want := []string{`Basic realm="faux", charset="UTF-8"`, `ApiKey realm="faux", in="query", name="api_key"`}
+	got := rr.Header().Values("WWW-Authenticate")
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Wrong WWW-Authenticate: got %q want %q", got, want)
+	}
+
+	rr = httptest.NewRecorder()
+	middleware.ServeHTTP(rr, httptest.NewRequest("GET", "/global", http.NoBody))
+	if got := rr.Header().Get("WWW-Authenticate"); got != `Bearer realm="faux"` {
+		t.Errorf("Wrong WWW-Authenticate: got %q want %q", got, `Bearer realm="faux"`)
+	}
+
+	// The server's token is also accepted as a bearer token.
+	req := httptest.NewRequest("GET", "/global", http.NoBody)
+	req.Header.Set("Authorization", "Bearer mytoken")
+	rr = httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidAuth(t *testing.T) {
+	testCases := []string{
+		`[{"path": "/a", "method": "GET", "auth": {}}]`,
+		`[{"path": "/a", "method": "GET", "auth": {"api_keys": [{"header": "X-Key"}]}}]`,
+		`[{"path": "/a", "method": "GET", "auth": {"basic": [{"password": "p"}]}}]`,
+	}
+	for _, routes := range testCases {
+		if err := NewRouter().LoadRoutesFromJSON([]byte(routes)); err == nil {
+			t.Errorf("Expected an error loading %s", routes)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// responseBody holds the body modes a route or magic request can specify.
+// Only one is sent: body_raw wins over body_base64, then body_file, body_json
+// and finally the legacy response_body.
+type responseBody struct {
+	Raw    string
+	Base64 string
+	File   string
+	JSON   interface{}
+	Legacy interface{}
+}
+
+func (b responseBody) isEmpty() bool {
+	return b.Raw == "" && b.Base64 == "" && b.File == "" && b.JSON == nil && (b.Legacy == nil || b.Legacy == http.NoBody)
+}
+
+// usesFile reports whether the body is streamed from File rather than encoded.
+func (b responseBody) usesFile() bool {
+	return b.File != "" && b.Raw == "" && b.Base64 == ""
+}
+
+// validate checks that a body file exists and base64 data decodes.
+func (b responseBody) validate() error {
+	if b.File != "" {
+		if _, err := os.Stat(b.File); err != nil {
+			return fmt.Errorf("invalid body_file: %w", err)
+		}
+	}
+	if b.Base64 != "" {
+		if _, err := base64.StdEncoding.DecodeString(b.Base64); err != nil {
+			return fmt.Errorf("invalid body_base64: %w", err)
+		}
+	}
+	return nil
+}
+
+// encode returns the bytes to send and the content type they imply.
+func (b responseBody) encode() ([]byte, string, error) {
+	switch {
+	case b.Raw != "":
+		data := []byte(b.Raw)
+		if json.Valid(data) {
+			return data, "application/json", nil
+		}
+		return data, http.DetectContentType(data), nil
+	case b.Base64 != "":
+		data, err := base64.StdEncoding.DecodeString(b.Base64)
+		if err != nil {
+			return nil, "", errors.New("Invalid base64 body")
+		}
+		return data, http.DetectContentType(data), nil
+	case b.JSON != nil:
+		data, err := json.Marshal(b.JSON)
+		return data, "application/json", err
+	case b.Legacy != nil && b.Legacy != http.NoBody:
+		data, err := json.Marshal(b.Legacy)
+		return data, "application/json", err
+	}
+	return nil, "", nil
+}
+
+// writeFileResponse streams the file at path as the response body. The file
+// is opened on every request, so edits show up without reloading the routes.
+func writeFileResponse(w http.ResponseWriter, statusCode int, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "Error reading body file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	if w.Header().Get("Content-Type") == "" {
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(statusCode)
+
+	// The status line is already out, so a failed copy cannot be reported.
+	_, _ = io.Copy(w, file)
+}
+
+func (route *Route) body() responseBody {
+	return responseBody{
+		Raw:    route.BodyRaw,
+		Base64: route.BodyBase64,
+		File:   route.BodyFile,
+		JSON:   route.BodyJSON,
+		Legacy: route.ResponseBody,
+	}
+}
+
+func (m *MagicRequest) body() responseBody {
+	return responseBody{
+		Raw:    m.BodyRaw,
+		Base64: m.BodyBase64,
+		JSON:   m.BodyJSON,
+		Legacy: m.ResponseBody,
+	}
+}
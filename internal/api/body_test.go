@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseBodyEncode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc                string
+		body                responseBody
+		expectedData        string
+		expectedContentType string
+		expectedErr         bool
+	}{
+		{
+			desc:                "Raw JSON is sent verbatim",
+			body:                responseBody{Raw: `{"message":"Hello"}`},
+			expectedData:        `{"message":"Hello"}`,
+			expectedContentType: "application/json",
+		},
+		{
+			desc:                "Raw XML",
+			body:                responseBody{Raw: `<?xml version="1.0"?><message>Hello</message>`},
+			expectedData:        `<?xml version="1.0"?><message>Hello</message>`,
+			expectedContentType: "text/xml; charset=utf-8",
+		},
+		{
+			desc:                "Base64",
+			body:                responseBody{Base64: "iVBORw0KGgo="},
+			expectedData:        "\x89PNG\r\n\x1a\n",
+			expectedContentType: "image/png",
+		},
+		{
+			desc:        "Invalid base64",
+			body:        responseBody{Base64: "not base64!"},
+			expectedErr: true,
+		},
+		{
+			desc:                "Structured JSON",
+			body:                responseBody{JSON: map[string]interface{}{"message": "Hello"}},
+			expectedData:        `{"message":"Hello"}`,
+			expectedContentType: "application/json",
+		},
+		{
+			desc:                "Raw wins over legacy body",
+			body:                responseBody{Raw: "plain", Legacy: "legacy"},
+			expectedData:        "plain",
+			expectedContentType: "text/plain; charset=utf-8",
+		},
+		{
+			desc: "Empty",
+			body: responseBody{Legacy: http.NoBody},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			data, contentType, err := tC.body.encode()
+			if (err != nil) != tC.expectedErr {
+				t.Fatalf("Expected error %v, but got %v", tC.expectedErr, err)
+			}
+			if string(data) != tC.expectedData {
+				t.Errorf("Expected data %q, but got %q", tC.expectedData, data)
+			}
+			if contentType != tC.expectedContentType {
+				t.Errorf("Expected content type %q, but got %q", tC.expectedContentType, contentType)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_RawBody(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:            "/feed",
+		Method:          "GET",
+		StatusCode:      200,
+		ResponseHeaders: map[string]string{"Content-Type": "text/csv"},
+		BodyRaw:         "id,name\n1,faux\n",
+	})
+
+	req := httptest.NewRequest("GET", "/feed", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if ctype := rr.Header().Get("Content-Type"); ctype != "text/csv" {
+		t.Errorf("Content type header does not match: got %v want %v", ctype, "text/csv")
+	}
+	if rr.Body.String() != "id,name\n1,faux\n" {
+		t.Errorf("Handler returned unexpected body: got %q", rr.Body.String())
+	}
+}
+
+func TestServeHTTP_MagicRawBody(t *testing.T) {
+	router := NewRouter()
+
+	jsonBody := strings.NewReader(`{"body_raw":"{\"message\":\"Hello\"}"}`)
+	req := httptest.NewRequest("POST", "/status/201", jsonBody)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if ctype := rr.Header().Get("Content-Type"); ctype != "application/json" {
+		t.Errorf("Content type header does not match: got %v want %v", ctype, "application/json")
+	}
+	if rr.Body.String() != `{"message":"Hello"}` {
+		t.Errorf("Handler returned unexpected body: got %v", rr.Body.String())
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidBase64(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[{"path": "/img", "method": "GET", "status_code": 200, "body_base64": "%%%"}]`))
+	if err == nil {
+		t.Errorf("Expected error for invalid body_base64")
+	}
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// curlValueFlags are the curl options that take the next argument as their
+// value and do not affect the route.
+var curlValueFlags = map[string]bool{
+	"-H": true, "--header": true, "-u": true, "--user": true,
+	"-A": true, "--user-agent": true, "-e": true, "--referer": true,
+	"-b": true, "--cookie": true, "-c": true, "--cookie-jar": true,
+	"-m": true, "--max-time": true, "--connect-timeout": true, "--retry": true,
+	"-F": true, "--form": true, "-x": true, "--proxy": true,
+	"-w": true, "--write-out": true, "-r": true, "--range": true,
+	"-E": true, "--cert": true, "--key": true, "--cacert": true,
+	"-T": true, "--upload-file": true, "-D": true, "--dump-header": true,
+	"-K": true, "--config": true, "-C": true, "--continue-at": true,
+	"-z": true, "--time-cond": true, "-U": true, "--proxy-user": true,
+	"-Y": true, "--speed-limit": true, "-y": true, "--speed-time": true,
+	"-Q": true, "--quote": true, "--resolve": true, "--connect-to": true,
+	"--interface": true, "--limit-rate": true, "--max-redirs": true,
+	"--max-filesize": true, "--retry-delay": true, "--retry-max-time": true,
+	"--proxy-header": true, "--noproxy": true, "--oauth2-bearer": true,
+	"--unix-socket": true, "--abstract-unix-socket": true, "--stderr": true,
+	"--trace": true, "--trace-ascii": true, "--capath": true, "--pass": true,
+	"--cert-type": true, "--key-type": true, "--ciphers": true,
+	"--etag-save": true, "--etag-compare": true, "--aws-sigv4": true,
+	"--url-query": true, "--request-target": true, "--local-port": true,
+}
+
+// curlDataFlags send a request body, which makes POST the default method.
+var curlDataFlags = map[string]bool{
+	"-d": true, "--data": true, "--data-raw": true, "--data-binary": true,
+	"--data-ascii": true, "--data-urlencode": true, "--json": true,
+}
+
+// takesCurlValue reports whether the curl option is followed by a value.
+func takesCurlValue(flag string) bool {
+	switch flag {
+	case "-X", "--request", "-o", "--output", "--url":
+		return true
+	}
+	return curlValueFlags[flag] || curlDataFlags[flag]
+}
+
+// MapCurlRoutes turns a text file of curl commands into routes. Commands may
+// span lines with a trailing backslash, and lines starting with # are
+// comments. Each command's response is read from the file it writes to with
+// -o or >, relative to baseDir: output saved with curl -i sets the status and
+// headers too, anything else is served as the body of a 200. A command
+// without an output file gets an empty 200.
+func MapCurlRoutes(text, baseDir string) ([]Route, error) {
+	commands, err := splitCurlCommands(text)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]Route, 0, len(commands))
+	for _, command := range commands {
+		route, err := curlRoute(command.args, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", command.line, err)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+type curlCommand struct {
+	line int
+	args []string
+}
+
+// splitCurlCommands splits text into commands and their arguments, following
+// shell quoting rules closely enough for commands copied from a terminal or
+// browser devtools.
+func splitCurlCommands(text string) ([]curlCommand, error) {
+	var commands []curlCommand
+	var args []string
+	var word strings.Builder
+	inWord := false
+	line, start := 1, 1
+
+	endWord := func() {
+		if inWord {
+			args = append(args, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(args) > 0 {
+			commands = append(commands, curlCommand{line: start, args: args})
+			args = nil
+		}
+	}
+
+	runes := []rune(strings.ReplaceAll(text, "\r\n", "\n"))
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if len(args) == 0 && !inWord {
+			start = line
+		}
+		switch {
+		case c == '\n':
+			line++
+			endCommand()
+		case c == ' ' || c == '\t':
+			endWord()
+		case c == '#' && !inWord:
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case c == '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] == '\n' {
+					line++
+					endWord()
+					continue
+				}
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case c == '\'':
+			inWord = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("line %d: unclosed single quote", line)
+			}
+			line += strings.Count(string(runes[i+1:end]), "\n")
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+		case c == '"':
+			inWord = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				word.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("line %d: unclosed double quote", line)
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	endCommand()
+
+	return commands, nil
+}
+
+// curlRoute builds a route from the arguments of one curl command.
+func curlRoute(args []string, baseDir string) (Route, error) {
+	if len(args) == 0 || args[0] != "curl" {
+		return Route{}, fmt.Errorf("not a curl command")
+	}
+
+	var method, target, output string
+	var data []string
+	get := false
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		// A value may be attached, as in --request=POST or -XPOST.
+		value, attached := "", false
+		if strings.HasPrefix(arg, "--") {
+			if name, v, ok := strings.Cut(arg, "="); ok {
+				arg, value, attached = name, v, true
+			}
+		} else if len(arg) > 2 && arg[0] == '-' && takesCurlValue(arg[:2]) {
+			arg, value, attached = arg[:2], arg[2:], true
+		}
+		next := func() (string, error) {
+			if attached {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s needs a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var err error
+		switch {
+		case arg == "-X" || arg == "--request":
+			method, err = next()
+		case arg == "-o" || arg == "--output" || arg == ">":
+			output, err = next()
+		case strings.HasPrefix(arg, ">") && len(arg) > 1:
+			output = arg[1:]
+		case arg == "--url":
+			target, err = next()
+		case arg == "-G" || arg == "--get":
+			get = true
+		case arg == "-I" || arg == "--head":
+			method = http.MethodHead
+		case curlDataFlags[arg]:
+			var value string
+			value, err = next()
+			data = append(data, value)
+		case curlValueFlags[arg]:
+			_, err = next()
+		case strings.HasPrefix(arg, "-"):
+			// Any other option is taken to be a switch such as -s or -L.
+		case target == "":
+			target = arg
+		}
+		if err != nil {
+			return Route{}, err
+		}
+	}
+
+	if target == "" {
+		return Route{}, fmt.Errorf("curl command has no URL")
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return Route{}, err
+	}
+	query := u.Query()
+	if get && len(data) > 0 {
+		extra, err := url.ParseQuery(strings.Join(data, "&"))
+		if err != nil {
+			return Route{}, err
+		}
+		for name, values := range extra {
+			query[name] = append(query[name], values...)
+		}
+	}
+	if method == "" {
+		method = http.MethodGet
+		if len(data) > 0 && !get {
+			method = http.MethodPost
+		}
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	route := Route{
+		Path:       path,
+		Method:     strings.ToUpper(method),
+		StatusCode: http.StatusOK,
+		Match:      queryMatch(query),
+	}
+	if output == "" {
+		return route, nil
+	}
+
+	resolved := output
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(baseDir, resolved)
+	}
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return Route{}, fmt.Errorf("response file: %w", err)
+	}
+	if !bytes.HasPrefix(content, []byte("HTTP/")) {
+		// Relative body files are resolved when the route is loaded.
+		route.BodyFile = output
+		return route, nil
+	}
+
+	status, header, body, err := parseCurlResponse(content)
+	if err != nil {
+		return Route{}, fmt.Errorf("response file %s: %w", output, err)
+	}
+	route.StatusCode = status
+	route.ResponseHeaders = stableHeaders(header)
+	route.BodyRaw, route.BodyBase64 = encodeBody(body)
+	return route, nil
+}
+
+// parseCurlResponse reads output saved with curl -i. Interim responses such as
+// 100 Continue are skipped. The body is taken as is, since curl has already
+// removed any chunked encoding.
+func parseCurlResponse(content []byte) (int, http.Header, []byte, error) {
+	for {
+		reader := bufio.NewReader(bytes.NewReader(content))
+		tp := textproto.NewReader(reader)
+
+		statusLine, err := tp.ReadLine()
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		fields := strings.Fields(statusLine)
+		if len(fields) < 2 {
+			return 0, nil, nil, fmt.Errorf("invalid status line %q", statusLine)
+		}
+		status, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("invalid status line %q", statusLine)
+		}
+		mimeHeader, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		var body bytes.Buffer
+		_, _ = reader.WriteTo(&body)
+		if status >= 100 && status < 200 && bytes.HasPrefix(body.Bytes(), []byte("HTTP/")) {
+			content = body.Bytes()
+			continue
+		}
+		return status, http.Header(mimeHeader), body.Bytes(), nil
+	}
+}
+
+// LoadRoutesFromCurl adds a route for every curl command in the file at path.
+func (r *Router) LoadRoutesFromCurl(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.loadRoutesCurl(data, path, filepath.Dir(path), make(map[string]string))
+}
+
+func (r *Router) loadRoutesCurl(data []byte, source, baseDir string, seen map[string]string) error {
+	routes, err := MapCurlRoutes(string(data), baseDir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	return r.addLoadedRoutes(routes, source, baseDir, seen)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const mockCurlCommands = `# Saved with curl -i
+curl -i https://api.example.com/users/1 -o user.http
+
+# Devtools style, spanning lines
+curl 'https://api.example.com/users?page=2' \
+  -H 'Accept: application/json' \
+  --compressed > users.json
+
+curl -X DELETE "https://api.example.com/users/1"
+curl -d '{"name": "a"}' https://api.example.com/users -o created.http
+`
+
+func writeCurlFixtures(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"api.curl":     mockCurlCommands,
+		"user.http":    "HTTP/1.1 100 Continue\r\n\r\nHTTP/2 200\r\ncontent-type: application/json\r\ndate: Mon, 01 Jan 2024 10:00:00 GMT\r\n\r\n{\"id\": 1}",
+		"users.json":   `[{"id": 1}]`,
+		"created.http": "HTTP/1.1 201 Created\nLocation: /users/2\n\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestMapCurlRoutes(t *testing.T) {
+	dir := writeCurlFixtures(t)
+
+	routes, err := MapCurlRoutes(mockCurlCommands, dir)
+	if err != nil {
+		t.Fatalf("MapCurlRoutes failed: %v", err)
+	}
+	if len(routes) != 4 {
+		t.Fatalf("Expected 4 routes, got %v", len(routes))
+	}
+
+	testCases := []struct {
+		desc   string
+		route  Route
+		method string
+		path   string
+		status int
+	}{
+		{desc: "curl -i output", route: routes[0], method: "GET", path: "/users/1", status: http.StatusOK},
+		{desc: "body file", route: routes[1], method: "GET", path: "/users", status: http.StatusOK},
+		{desc: "no output", route: routes[2], method: "DELETE", path: "/users/1", status: http.StatusOK},
+		{desc: "data implies POST", route: routes[3], method: "POST", path: "/users", status: http.StatusCreated},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if tC.route.Method != tC.method || tC.route.Path != tC.path || tC.route.StatusCode != tC.status {
+				t.Errorf("got %v %v %v want %v %v %v", tC.route.Method, tC.route.Path, tC.route.StatusCode, tC.method, tC.path, tC.status)
+			}
+		})
+	}
+
+	if routes[0].BodyRaw != `{"id": 1}` || routes[0].ResponseHeaders["Content-Type"] != "application/json" || routes[0].ResponseHeaders["Date"] != "" {
+		t.Errorf("Unexpected response from curl -i output: %+v", routes[0])
+	}
+	if routes[1].BodyFile != "users.json" || routes[1].Match.Query["page"].Equals != "2" {
+		t.Errorf("Unexpected body file route: %+v", routes[1])
+	}
+	if routes[3].ResponseHeaders["Location"] != "/users/2" {
+		t.Errorf("Unexpected headers: %v", routes[3].ResponseHeaders)
+	}
+}
+
+func TestMapCurlRoutes_Flags(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		text   string
+		method string
+		path   string
+	}{
+		{desc: "request with equals", text: "curl --request=PUT https://example.com/a", method: "PUT", path: "/a"},
+		{desc: "attached short value", text: "curl -XPATCH https://example.com/a", method: "PATCH", path: "/a"},
+		{desc: "data with equals", text: "curl --data=x=1 https://example.com/a", method: "POST", path: "/a"},
+		{desc: "attached data", text: "curl -dx=1 https://example.com/a", method: "POST", path: "/a"},
+		{desc: "url with equals", text: "curl --url=https://example.com/b", method: "GET", path: "/b"},
+		{desc: "upload file", text: "curl -T file.txt https://example.com/a", method: "GET", path: "/a"},
+		{desc: "dump header", text: "curl -D headers.txt --max-redirs 3 https://example.com/a", method: "GET", path: "/a"},
+		{desc: "unknown switch with equals", text: "curl --retry-all-errors --header=X-A:1 https://example.com/a", method: "GET", path: "/a"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			routes, err := MapCurlRoutes(tC.text, t.TempDir())
+			if err != nil {
+				t.Fatalf("MapCurlRoutes failed: %v", err)
+			}
+			if routes[0].Method != tC.method || routes[0].Path != tC.path {
+				t.Errorf("got %v %v want %v %v", routes[0].Method, routes[0].Path, tC.method, tC.path)
+			}
+		})
+	}
+}
+
+func TestMapCurlRoutes_Errors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		text string
+	}{
+		{desc: "not curl", text: "wget https://example.com"},
+		{desc: "no URL", text: "curl -s"},
+		{desc: "unclosed quote", text: "curl 'https://example.com"},
+		{desc: "missing response file", text: "curl https://example.com -o missing.json"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if _, err := MapCurlRoutes(tC.text, t.TempDir()); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadRoutesFromCurl(t *testing.T) {
+	dir := writeCurlFixtures(t)
+
+	router := NewRouter()
+	if err := router.LoadRoutesFromCurl(filepath.Join(dir, "api.curl")); err != nil {
+		t.Fatalf("LoadRoutesFromCurl failed: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users?page=2", http.NoBody))
+	if rr.Code != http.StatusOK || rr.Body.String() != `[{"id": 1}]` {
+		t.Errorf("Body file was not served: %v %v", rr.Code, rr.Body.String())
+	}
+	if status := serveStatus(router, "POST", "/users"); status != http.StatusCreated {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+}
@@ -4,18 +4,15 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 func (r *Router) LoadRoutesFromFiles(files []string) error {
+	seen := make(map[string]string)
 	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			return err
-		}
-
-		if err := r.LoadRoutesFromJSON(data); err != nil {
+		if err := r.loadRoutesFile(file, seen); err != nil {
 			return err
 		}
 	}
@@ -28,21 +25,111 @@ func (r *Router) LoadRoutesFromDir(dir string) error {
 		return err
 	}
 
+	seen := make(map[string]string)
+	// A JSON file that is not a routes file may be a body or JWKS file kept
+	// next to the routes, which is only known once the routes using it are
+	// loaded.
+	var failed []string
+	var errs []error
 	for _, file := range files {
-		if filepath.Ext(file.Name()) != ".json" {
+		if !isRoutesFile(file.Name()) {
 			continue
 		}
 
-		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		path := filepath.Join(dir, file.Name())
+		if err := r.loadRoutesFile(path, seen); err != nil {
+			if filepath.Ext(path) != ".json" {
+				return err
+			}
+			failed = append(failed, path)
+			errs = append(errs, err)
+		}
+	}
+	for i, path := range failed {
+		if !r.isBodyFile(path) && !r.isJWKSFile(path) {
+			return errs[i]
+		}
+	}
+	return nil
+}
+
+// isRoutesFile reports whether name looks like a file routes can be loaded
+// from: a JSON routes file, a Postman collection, a HAR capture or a list of
+// curl commands.
+func isRoutesFile(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == ".json" || ext == ".har" || ext == ".curl"
+}
+
+// loadRoutesFile loads the routes in path according to its extension.
+func (r *Router) loadRoutesFile(path string, seen map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case filepath.Ext(path) == ".har":
+		return r.loadRoutesHAR(data, path, seen)
+	case filepath.Ext(path) == ".curl":
+		return r.loadRoutesCurl(data, path, filepath.Dir(path), seen)
+	case strings.HasSuffix(path, ".postman_collection.json"):
+		collection, err := parsePostman(data)
 		if err != nil {
 			return err
 		}
+		return r.addPostmanRoutes(collection, path, seen)
+	}
+	return r.loadRoutesJSON(data, path, filepath.Dir(path), seen)
+}
+
+// isBodyFile reports whether any route streams its body from path.
+func (r *Router) isBodyFile(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := func(file string) bool {
+		if file == "" {
+			return false
+		}
+		abs, err := filepath.Abs(file)
+		return err == nil && abs == path
+	}
+	for _, route := range r.Routes {
+		if matches(route.BodyFile) {
+			return true
+		}
+		for _, resp := range route.Responses {
+			if matches(resp.BodyFile) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-		if err := r.LoadRoutesFromJSON(data); err != nil {
-			return err
+// isJWKSFile reports whether any route reads its JWT keys from path.
+func (r *Router) isJWKSFile(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, route := range r.Routes {
+		if route.Auth == nil || route.Auth.JWT == nil || route.Auth.JWT.JWKS == "" {
+			continue
+		}
+		if jwks, err := filepath.Abs(route.Auth.JWT.JWKS); err == nil && jwks == path {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
 // WatchRoutes sets up a watcher on the routes file or directory.
@@ -68,6 +155,13 @@ func WatchRoutes(router *Router, routesFilePath string) {
 
 				// Check if event is caused by a file write.
 				if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
+					// Body files are read on every request, so there is nothing to
+					// reload. JWKS files are read with the routes that use them.
+					if !isRoutesFile(event.Name) || router.isBodyFile(event.Name) || router.isJWKSFile(event.Name) {
+						log.Println("Modified file:", event.Name)
+						continue
+					}
+
 					log.Println("Modified file:", event.Name, " reloading...")
 
 					// Load routes again.
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -150,7 +153,7 @@ func TestWatchRoutes(t *testing.T) {
 	time.Sleep(1 * time.Second)
 
 	// Check if the route has been updated
-	route, ok := router.Routes["/test"]
+	route, ok := router.Routes["GET /test"]
 	if !ok || route.StatusCode != 201 {
 		t.Errorf("Routes were not updated after file modification")
 	}
@@ -161,3 +164,101 @@ func TestWatchRoutes(t *testing.T) {
 		t.Errorf("os.Remove failed after test.")
 	}
 }
+
+func TestLoadRoutesFromDir_BodyFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "fixtures"), os.ModePerm); err != nil {
+		t.Fatalf("Failed to create fixtures dir: %v", err)
+	}
+	fixture := filepath.Join(dir, "fixtures", "user.xml")
+	if err := os.WriteFile(fixture, []byte("<user>1</user>"), 0o644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "routes.json"), []byte(`[
+		{
+			"path": "/user",
+			"method": "GET",
+			"status_code": 200,
+			"body_file": "fixtures/user.xml"
+		}
+	]`), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+
+	router := NewRouter()
+	if err := router.LoadRoutesFromDir(dir); err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/user", http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if ctype := rr.Header().Get("Content-Type"); ctype != "text/xml; charset=utf-8" {
+		t.Errorf("Content type header does not match: got %v", ctype)
+	}
+	if rr.Body.String() != "<user>1</user>" {
+		t.Errorf("Handler returned unexpected body: got %v", rr.Body.String())
+	}
+
+	// The file is read on every request, so changes show up right away.
+	if err := os.WriteFile(fixture, []byte("<user>2</user>"), 0o644); err != nil {
+		t.Fatalf("Failed to update fixture: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Body.String() != "<user>2</user>" {
+		t.Errorf("Handler returned stale body: got %v", rr.Body.String())
+	}
+	if !router.isBodyFile(fixture) {
+		t.Errorf("Fixture not recognised as a body file")
+	}
+}
+
+func TestLoadRoutesFromDir_JSONBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	// The fixture sorts before the routes file that uses it.
+	if err := os.WriteFile(filepath.Join(dir, "a_user.json"), []byte(`{"id": 1}`), 0o644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "routes.json"), []byte(`[
+		{"path": "/user", "method": "GET", "status_code": 200, "body_file": "a_user.json"}
+	]`), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+
+	router := NewRouter()
+	if err := router.LoadRoutesFromDir(dir); err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/user", http.NoBody))
+	if rr.Body.String() != `{"id": 1}` {
+		t.Errorf("Handler returned unexpected body: got %v", rr.Body.String())
+	}
+
+	// A JSON file no route uses is still reported.
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"id": 2}`), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := NewRouter().LoadRoutesFromDir(dir); err == nil {
+		t.Errorf("Expected an error for a JSON file that is neither routes nor a body file")
+	}
+}
+
+func TestLoadRoutesFromFiles_MissingBodyFile(t *testing.T) {
+	dir := t.TempDir()
+	routesFile := filepath.Join(dir, "routes.json")
+	err := os.WriteFile(routesFile, []byte(`[{"path": "/user", "method": "GET", "status_code": 200, "body_file": "missing.json"}]`), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+
+	router := NewRouter()
+	if err := router.LoadRoutesFromFiles([]string{routesFile}); err == nil {
+		t.Errorf("Expected error for missing body file")
+	}
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// HAR is an HTTP Archive 1.2 document, as saved by browser devtools. Only
+// the fields Faux reads or writes are declared.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is a response body. Text is base64 when Encoding says so.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harHeaders lists the headers that do not describe a HAR body as it is
+// stored. Browsers save bodies decoded, so the encoding has to go too.
+var harHeaders = []string{"Content-Encoding"}
+
+// ParseHAR decodes a HAR document.
+func ParseHAR(data []byte) (*HAR, error) {
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+	return &har, nil
+}
+
+// MapHARRoutes turns HAR entries into routes. Entries that share a method,
+// path and query become one route whose responses are served in the order
+// they were captured. Entries the browser never got a response for are
+// skipped.
+func MapHARRoutes(har *HAR) ([]Route, error) {
+	var routes []*Route
+	byKey := make(map[string]*Route)
+
+	for i, entry := range har.Log.Entries {
+		if entry.Response.Status == 0 {
+			continue
+		}
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("HAR entry %d: %w", i, err)
+		}
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+
+		response, err := harResponse(entry.Response)
+		if err != nil {
+			return nil, fmt.Errorf("HAR entry %d: %w", i, err)
+		}
+
+		key := recordKey(entry.Request.Method, path, u.Query())
+		if route, ok := byKey[key]; ok {
+			route.Responses = append(route.Responses, response)
+			continue
+		}
+		route := &Route{
+			Path:      path,
+			Method:    entry.Request.Method,
+			Match:     queryMatch(u.Query()),
+			Responses: []RouteResponse{response},
+		}
+		byKey[key] = route
+		routes = append(routes, route)
+	}
+
+	result := make([]Route, 0, len(routes))
+	for _, route := range routes {
+		// A single response is kept on the route itself.
+		if len(route.Responses) == 1 {
+			response := route.Responses[0]
+			route.StatusCode = response.StatusCode
+			route.ResponseHeaders = response.ResponseHeaders
+			route.BodyRaw = response.BodyRaw
+			route.BodyBase64 = response.BodyBase64
+			route.Responses = nil
+		} else {
+			route.ResponseMode = ResponseModeSequence
+		}
+		result = append(result, *route)
+	}
+	return result, nil
+}
+
+func harResponse(resp HARResponse) (RouteResponse, error) {
+	header := make(http.Header)
+	for _, h := range resp.Headers {
+		// HTTP/2 captures include pseudo-headers such as :status.
+		if h.Name == "" || h.Name[0] == ':' {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	for _, name := range harHeaders {
+		header.Del(name)
+	}
+	if header.Get("Content-Type") == "" && resp.Content.MimeType != "" {
+		header.Set("Content-Type", resp.Content.MimeType)
+	}
+
+	response := RouteResponse{
+		StatusCode:      resp.Status,
+		ResponseHeaders: stableHeaders(header),
+	}
+	if resp.Content.Encoding == "base64" {
+		if _, err := base64.StdEncoding.DecodeString(resp.Content.Text); err != nil {
+			return RouteResponse{}, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		response.BodyBase64 = resp.Content.Text
+	} else {
+		response.BodyRaw = resp.Content.Text
+	}
+	return response, nil
+}
+
+// LoadRoutesFromHAR adds a route for every exchange in a HAR document.
+func (r *Router) LoadRoutesFromHAR(data []byte) error {
+	return r.loadRoutesHAR(data, "HAR input", make(map[string]string))
+}
+
+func (r *Router) loadRoutesHAR(data []byte, source string, seen map[string]string) error {
+	har, err := ParseHAR(data)
+	if err != nil {
+		return err
+	}
+	routes, err := MapHARRoutes(har)
+	if err != nil {
+		return err
+	}
+	return r.addLoadedRoutes(routes, source, "", seen)
+}
+
+// ExportHAR returns journal entries as a HAR document, oldest first, so a
+// mocked session can be opened in browser devtools.
+func ExportHAR(entries []JournalEntry) *HAR {
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "faux", Version: "1.0"},
+		Entries: make([]HAREntry, 0, len(entries)),
+	}}
+
+	for _, entry := range entries {
+		u, _ := url.Parse(entry.URL)
+		if u == nil {
+			u = &url.URL{Path: entry.Path}
+		}
+		if u.Host == "" {
+			u.Scheme = "http"
+			u.Host = entry.Host
+			if u.Host == "" {
+				u.Host = "localhost"
+			}
+		}
+
+		request := HARRequest{
+			Method:      entry.Method,
+			URL:         u.String(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     harNameValues(entry.Headers),
+			QueryString: harNameValues(http.Header(u.Query())),
+			HeadersSize: -1,
+			BodySize:    len(entry.Body),
+		}
+		if entry.Body != "" {
+			request.PostData = &HARPostData{MimeType: entry.Headers.Get("Content-Type"), Text: entry.Body}
+		}
+
+		raw, encoded := encodeBody([]byte(entry.ResponseBody))
+		content := HARContent{
+			Size:     len(entry.ResponseBody),
+			MimeType: entry.ResponseHeaders.Get("Content-Type"),
+			Text:     raw,
+		}
+		if encoded != "" {
+			content.Text = encoded
+			content.Encoding = "base64"
+		}
+
+		duration := float64(entry.Duration) / float64(time.Millisecond)
+		har.Log.Entries = append(har.Log.Entries, HAREntry{
+			StartedDateTime: entry.Time,
+			Time:            duration,
+			Request:         request,
+			Response: HARResponse{
+				Status:      entry.Status,
+				StatusText:  http.StatusText(entry.Status),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []HARNameValue{},
+				Headers:     harNameValues(entry.ResponseHeaders),
+				Content:     content,
+				RedirectURL: entry.ResponseHeaders.Get("Location"),
+				HeadersSize: -1,
+				BodySize:    len(entry.ResponseBody),
+			},
+			Timings: HARTimings{Wait: duration},
+		})
+	}
+
+	sort.SliceStable(har.Log.Entries, func(i, j int) bool {
+		return har.Log.Entries[i].StartedDateTime.Before(har.Log.Entries[j].StartedDateTime)
+	})
+	return har
+}
+
+func harNameValues(header http.Header) []HARNameValue {
+	pairs := []HARNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, HARNameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
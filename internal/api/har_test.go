@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mockHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "Browser", "version": "1"},
+    "entries": [
+      {
+        "startedDateTime": "2024-01-01T10:00:00.000Z",
+        "request": {"method": "GET", "url": "https://api.example.com/jobs/7?verbose=1", "headers": []},
+        "response": {
+          "status": 202,
+          "headers": [
+            {"name": ":status", "value": "202"},
+            {"name": "content-type", "value": "application/json"},
+            {"name": "content-encoding", "value": "gzip"},
+            {"name": "date", "value": "Mon, 01 Jan 2024 10:00:00 GMT"}
+          ],
+          "content": {"size": 19, "mimeType": "application/json", "text": "{\"state\":\"running\"}"}
+        }
+      },
+      {
+        "startedDateTime": "2024-01-01T10:00:01.000Z",
+        "request": {"method": "GET", "url": "https://api.example.com/jobs/7?verbose=1", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "Content-Type", "value": "application/json"}],
+          "content": {"size": 16, "mimeType": "application/json", "text": "{\"state\":\"done\"}"}
+        }
+      },
+      {
+        "startedDateTime": "2024-01-01T10:00:02.000Z",
+        "request": {"method": "GET", "url": "https://cdn.example.com/pixel.gif", "headers": []},
+        "response": {
+          "status": 200,
+          "headers": [],
+          "content": {"size": 3, "mimeType": "image/gif", "text": "R0lG", "encoding": "base64"}
+        }
+      },
+      {
+        "startedDateTime": "2024-01-01T10:00:03.000Z",
+        "request": {"method": "POST", "url": "https://api.example.com/blocked", "headers": []},
+        "response": {"status": 0, "headers": [], "content": {"size": 0, "mimeType": ""}}
+      }
+    ]
+  }
+}`
+
+func TestMapHARRoutes(t *testing.T) {
+	har, err := ParseHAR([]byte(mockHAR))
+	if err != nil {
+		t.Fatalf("ParseHAR failed: %v", err)
+	}
+	routes, err := MapHARRoutes(har)
+	if err != nil {
+		t.Fatalf("MapHARRoutes failed: %v", err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %v", len(routes))
+	}
+
+	jobs := routes[0]
+	if jobs.Path != "/jobs/7" || jobs.Match == nil || jobs.Match.Query["verbose"].Equals != "1" {
+		t.Errorf("Unexpected route: %+v", jobs)
+	}
+	if jobs.ResponseMode != ResponseModeSequence || len(jobs.Responses) != 2 {
+		t.Fatalf("Repeated requests should become a sequence: %+v", jobs)
+	}
+	first := jobs.Responses[0]
+	if first.StatusCode != http.StatusAccepted || first.BodyRaw != `{"state":"running"}` {
+		t.Errorf("Unexpected first response: %+v", first)
+	}
+	expectedHeaders := map[string]string{"Content-Type": "application/json"}
+	if len(first.ResponseHeaders) != 1 || first.ResponseHeaders["Content-Type"] != expectedHeaders["Content-Type"] {
+		t.Errorf("Headers not filtered: got %v want %v", first.ResponseHeaders, expectedHeaders)
+	}
+
+	pixel := routes[1]
+	if pixel.StatusCode != http.StatusOK || pixel.BodyBase64 != "R0lG" || pixel.ResponseHeaders["Content-Type"] != "image/gif" {
+		t.Errorf("Unexpected single response route: %+v", pixel)
+	}
+}
+
+func TestLoadRoutesFromHAR(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "session.har"), []byte(mockHAR), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	router := NewRouter()
+	if err := router.LoadRoutesFromDir(dir); err != nil {
+		t.Fatalf("LoadRoutesFromDir failed: %v", err)
+	}
+
+	for _, expected := range []int{http.StatusAccepted, http.StatusOK} {
+		if status := serveStatus(router, "GET", "/jobs/7?verbose=1"); status != expected {
+			t.Errorf("Handler returned wrong status code: got %v want %v", status, expected)
+		}
+	}
+
+	if err := NewRouter().LoadRoutesFromHAR([]byte(`{"log": `)); err == nil {
+		t.Errorf("Expected error for an invalid HAR document")
+	}
+}
+
+func TestExportHAR(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/users/{id}", Method: "POST", StatusCode: http.StatusCreated, BodyRaw: `{"ok":true}`})
+
+	req := httptest.NewRequest("POST", "/users/1?notify=yes", strings.NewReader(`{"name":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serveAndRecord(router, req)
+
+	rr := adminRequest(t, router, "GET", AdminPrefix+"requests?format=har", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var har HAR
+	if err := json.Unmarshal(rr.Body.Bytes(), &har); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("Unexpected HAR log: %+v", har.Log)
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.URL != "http://example.com/users/1?notify=yes" || entry.Request.PostData == nil || entry.Request.PostData.Text != `{"name":"a"}` {
+		t.Errorf("Unexpected request: %+v", entry.Request)
+	}
+	if entry.Response.Status != http.StatusCreated || entry.Response.Content.Text != `{"ok":true}` {
+		t.Errorf("Unexpected response: %+v", entry.Response)
+	}
+
+	// The export loads back as routes.
+	routes, err := MapHARRoutes(&har)
+	if err != nil || len(routes) != 1 || routes[0].Path != "/users/1" || routes[0].BodyRaw != `{"ok":true}` {
+		t.Errorf("Exported HAR did not map back to the route: %+v %v", routes, err)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultJournalSize is how many requests a journal keeps unless told
+// otherwise.
+const DefaultJournalSize = 1000
+
+// MaxJournalResponseBody caps how much of each response body is journaled.
+const MaxJournalResponseBody = 64 << 10
+
+// MaxJournalRequestBody caps how much of each request body is journaled.
+const MaxJournalRequestBody = 64 << 10
+
+// JournalEntry is one request as recorded in the journal.
+type JournalEntry struct {
+	ID      string      `json:"id"`
+	Time    time.Time   `json:"time"`
+	Method  string      `json:"method"`
+	Host    string      `json:"host,omitempty"`
+	URL     string      `json:"url"`
+	Path    string      `json:"path"`
+	Headers http.Header `json:"headers"`
+	// Body is cut off after MaxJournalRequestBody bytes.
+	Body     string        `json:"body,omitempty"`
+	RouteID  string        `json:"route_id,omitempty"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration_ns"`
+	// ResponseHeaders and ResponseBody are what was sent back. The body is
+	// cut off after MaxJournalResponseBody bytes.
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+	ResponseBody    string      `json:"response_body,omitempty"`
+}
+
+// Journal keeps the most recent requests in a fixed-size ring buffer.
+type Journal struct {
+	mu      sync.Mutex
+	entries []JournalEntry
+	next    int
+	full    bool
+}
+
+// NewJournal returns a journal that holds up to size requests.
+func NewJournal(size int) *Journal {
+	if size <= 0 {
+		size = DefaultJournalSize
+	}
+	return &Journal{entries: make([]JournalEntry, size)}
+}
+
+// StartEntry begins a journal entry for req. The start of the body is read
+// for the entry and put back in front of the rest, and the returned request
+// lets the router note the route it matched. Set Status and Duration once the
+// request has been served, then Add the entry.
+func StartEntry(req *http.Request) (*http.Request, *JournalEntry) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		body, _ = io.ReadAll(io.LimitReader(req.Body, MaxJournalRequestBody))
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+	}
+	entry := &JournalEntry{
+		ID:      newUUID(),
+		Time:    time.Now(),
+		Method:  req.Method,
+		Host:    req.Host,
+		URL:     req.URL.String(),
+		Path:    req.URL.Path,
+		Headers: req.Header.Clone(),
+		Body:    string(body),
+	}
+	return req.WithContext(context.WithValue(req.Context(), journalEntryKey, entry)), entry
+}
+
+// noteRoute records the matched route on the request's journal entry, if it
+// has one.
+func noteRoute(req *http.Request, route *Route) {
+	if entry, ok := req.Context().Value(journalEntryKey).(*JournalEntry); ok {
+		entry.RouteID = route.ID
+	}
+}
+
+// Add records an entry, dropping the oldest one once the journal is full.
+func (j *Journal) Add(entry *JournalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries[j.next] = *entry
+	j.next = (j.next + 1) % len(j.entries)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// Entries returns the recorded requests, oldest first.
+func (j *Journal) Entries() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.full {
+		return append([]JournalEntry(nil), j.entries[:j.next]...)
+	}
+	entries := make([]JournalEntry, 0, len(j.entries))
+	entries = append(entries, j.entries[j.next:]...)
+	return append(entries, j.entries[:j.next]...)
+}
+
+// Clear removes every recorded request.
+func (j *Journal) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = make([]JournalEntry, len(j.entries))
+	j.next = 0
+	j.full = false
+}
+
+// JournalFilter selects journal entries. Zero fields match everything.
+type JournalFilter struct {
+	// Path is a route path pattern, so /users/{id} and /users/** work.
+	Path    string
+	Method  string
+	Headers map[string]string
+	Since   time.Time
+	Until   time.Time
+}
+
+// Find returns the recorded requests that pass the filter, oldest first.
+func (j *Journal) Find(filter JournalFilter) []JournalEntry {
+	var found []JournalEntry
+	for _, entry := range j.Entries() {
+		if filter.matches(&entry) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+func (f JournalFilter) matches(entry *JournalEntry) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, entry.Method) {
+		return false
+	}
+	if f.Path != "" {
+		if _, ok := matchPath(f.Path, entry.Path); !ok {
+			return false
+		}
+	}
+	for name, value := range f.Headers {
+		if entry.Headers.Get(name) != value {
+			return false
+		}
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func (r *Router) serveRequests(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		query := req.URL.Query()
+		filter := JournalFilter{
+			Path:    query.Get("path"),
+			Method:  query.Get("method"),
+			Headers: make(map[string]string),
+		}
+		for _, header := range query["header"] {
+			name, value, ok := strings.Cut(header, ":")
+			if !ok {
+				http.Error(w, "header filters must look like Name:Value", http.StatusBadRequest)
+				return
+			}
+			filter.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		for param, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+			if value := query.Get(param); value != "" {
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					http.Error(w, param+" must be an RFC 3339 time", http.StatusBadRequest)
+					return
+				}
+				*target = t
+			}
+		}
+
+		entries := r.Journal.Find(filter)
+		if entries == nil {
+			entries = []JournalEntry{}
+		}
+		if query.Get("format") == "har" {
+			writeJSON(w, http.StatusOK, ExportHAR(entries))
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+	case http.MethodDelete:
+		r.Journal.Clear()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
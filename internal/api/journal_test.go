@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveAndRecord serves a request the way the server does, recording it in
+// the router's journal.
+func serveAndRecord(router *Router, req *http.Request) *httptest.ResponseRecorder {
+	req, entry := StartEntry(req)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	entry.Status = rr.Code
+	entry.ResponseHeaders = rr.Header().Clone()
+	entry.ResponseBody = rr.Body.String()
+	router.Journal.Add(entry)
+	return rr
+}
+
+func TestStartEntry_LargeBody(t *testing.T) {
+	body := strings.Repeat("x", MaxJournalRequestBody+100)
+	req, entry := StartEntry(httptest.NewRequest("POST", "/upload", strings.NewReader(body)))
+
+	if len(entry.Body) != MaxJournalRequestBody {
+		t.Errorf("Journaled body not capped: got %v bytes want %v", len(entry.Body), MaxJournalRequestBody)
+	}
+	data, err := readBody(req)
+	if err != nil || string(data) != body {
+		t.Errorf("Handler did not get the full body: got %v bytes want %v", len(data), len(body))
+	}
+}
+
+func TestJournalRingBuffer(t *testing.T) {
+	journal := NewJournal(3)
+	for i := 0; i < 5; i++ {
+		journal.Add(&JournalEntry{Path: fmt.Sprintf("/%d", i)})
+	}
+
+	entries := journal.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected three entries, got %v", len(entries))
+	}
+	for i, entry := range entries {
+		if expected := fmt.Sprintf("/%d", i+2); entry.Path != expected {
+			t.Errorf("Entry %d: got path %v want %v", i, entry.Path, expected)
+		}
+	}
+
+	journal.Clear()
+	if len(journal.Entries()) != 0 {
+		t.Errorf("Journal not cleared")
+	}
+}
+
+func TestJournalFilter(t *testing.T) {
+	now := time.Now()
+	journal := NewJournal(10)
+	journal.Add(&JournalEntry{Method: "GET", Path: "/users/1", Time: now.Add(-time.Hour), Headers: http.Header{}})
+	journal.Add(&JournalEntry{Method: "POST", Path: "/users", Time: now, Headers: http.Header{"X-Tenant": {"a"}}})
+	journal.Add(&JournalEntry{Method: "GET", Path: "/users/2", Time: now, Headers: http.Header{"X-Tenant": {"b"}}})
+
+	testCases := []struct {
+		desc     string
+		filter   JournalFilter
+		expected int
+	}{
+		{"No filter", JournalFilter{}, 3},
+		{"Method", JournalFilter{Method: "get"}, 2},
+		{"Path pattern", JournalFilter{Path: "/users/{id}"}, 2},
+		{"Header", JournalFilter{Headers: map[string]string{"x-tenant": "a"}}, 1},
+		{"Since", JournalFilter{Since: now.Add(-time.Minute)}, 2},
+		{"Until", JournalFilter{Until: now.Add(-time.Minute)}, 1},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if found := journal.Find(tC.filter); len(found) != tC.expected {
+				t.Errorf("Expected %d entries, got %d", tC.expected, len(found))
+			}
+		})
+	}
+}
+
+func TestAdminRequests(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/orders", Method: "POST", StatusCode: http.StatusCreated})
+	route := router.RouteList()[0]
+
+	req := httptest.NewRequest("POST", "/orders", strings.NewReader(`{"item": "book"}`))
+	req.Header.Set("X-Tenant", "a")
+	serveAndRecord(router, req)
+	serveAndRecord(router, httptest.NewRequest("GET", "/missing", http.NoBody))
+
+	rr := adminRequest(t, router, "GET", AdminPrefix+"requests?method=POST&header=X-Tenant:a", "")
+	var entries []JournalEntry
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Failed to decode entries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %v", rr.Body.String())
+	}
+
+	entry := entries[0]
+	if entry.Body != `{"item": "book"}` || entry.Status != http.StatusCreated || entry.RouteID != route.ID {
+		t.Errorf("Entry not recorded correctly: %+v", entry)
+	}
+
+	if rr = adminRequest(t, router, "GET", AdminPrefix+"requests?since=yesterday", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad time, got %v", rr.Code)
+	}
+
+	if rr = adminRequest(t, router, "DELETE", AdminPrefix+"requests", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned wrong status code: got %v", rr.Code)
+	}
+	if len(router.Journal.Entries()) != 0 {
+		t.Errorf("Journal not cleared")
+	}
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// JWTAuth accepts JSON Web Tokens sent as bearer tokens. Signatures are
+// checked with HS256 against Secrets, or with HS256, RS256 or ES256 against
+// the keys in a local JWKS file. Routes add the scopes and claim values a
+// token must carry.
+type JWTAuth struct {
+	Secrets []string `json:"secrets,omitempty"`
+	// JWKS is the path of a JSON Web Key Set file. A relative path is
+	// resolved against the directory of the routes file.
+	JWKS     string `json:"jwks,omitempty"`
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	// Leeway is how many seconds exp and nbf may be off by.
+	Leeway int `json:"leeway,omitempty"`
+	// Scopes must all be in the token's scope or scp claim.
+	Scopes []string `json:"scopes,omitempty"`
+	// Claims maps claim names to the value they must have. A dotted name
+	// reaches into nested objects, and an array claim must contain the value.
+	Claims map[string]interface{} `json:"claims,omitempty"`
+
+	keys []jwtKey
+}
+
+// jwtKey is a key a signature can be checked with: a []byte secret, an
+// *rsa.PublicKey or an *ecdsa.PublicKey.
+type jwtKey struct {
+	id  string
+	key interface{}
+}
+
+// jwtError is why a token was refused, with the RFC 6750 error code to
+// report it with.
+type jwtError struct {
+	code        string
+	description string
+}
+
+func (e *jwtError) Error() string {
+	return e.description
+}
+
+// RFC 6750 error codes.
+const (
+	jwtInvalidToken      = "invalid_token"
+	jwtInsufficientScope = "insufficient_scope"
+)
+
+// hasKeys reports whether the JWT auth has keys of its own, rather than
+// relying on the server's.
+func (j *JWTAuth) hasKeys() bool {
+	return len(j.Secrets) > 0 || j.JWKS != ""
+}
+
+// LoadKeys reads the secrets and the JWKS file. It is called when routes are
+// loaded, and must be called on the server-wide JWT auth before it is used.
+func (j *JWTAuth) LoadKeys() error {
+	keys := make([]jwtKey, 0, len(j.Secrets))
+	for _, secret := range j.Secrets {
+		if secret == "" {
+			return errors.New("empty secret")
+		}
+		keys = append(keys, jwtKey{key: []byte(secret)})
+	}
+	if j.JWKS != "" {
+		jwks, err := loadJWKS(j.JWKS)
+		if err != nil {
+			return fmt.Errorf("jwks %s: %w", j.JWKS, err)
+		}
+		keys = append(keys, jwks...)
+	}
+	j.keys = keys
+	return nil
+}
+
+// resolveJWKS makes a relative JWKS path relative to baseDir.
+func (j *JWTAuth) resolveJWKS(baseDir string) {
+	if j.JWKS != "" && !filepath.IsAbs(j.JWKS) {
+		j.JWKS = filepath.Join(baseDir, j.JWKS)
+	}
+}
+
+// withKeys returns the route's JWT auth with the server's keys, issuer and
+// audience filled in where the route sets none.
+func (j *JWTAuth) withKeys(server *JWTAuth) *JWTAuth {
+	if server == nil || j.hasKeys() {
+		return j
+	}
+	merged := *j
+	merged.keys = server.keys
+	if merged.Issuer == "" {
+		merged.Issuer = server.Issuer
+	}
+	if merged.Audience == "" {
+		merged.Audience = server.Audience
+	}
+	if merged.Leeway == 0 {
+		merged.Leeway = server.Leeway
+	}
+	return &merged
+}
+
+// verify checks the token's signature, lifetime, issuer and audience, then
+// the scopes and claims the route requires, and returns its claims.
+func (j *JWTAuth) verify(token string, now time.Time) (map[string]interface{}, *jwtError) {
+	invalid := func(format string, args ...interface{}) (map[string]interface{}, *jwtError) {
+		return nil, &jwtError{code: jwtInvalidToken, description: fmt.Sprintf(format, args...)}
+	}
+
+	if len(j.keys) == 0 {
+		return invalid("no keys to verify the token with")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return invalid("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return invalid("malformed header")
+	}
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return invalid("malformed claims")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return invalid("malformed signature")
+	}
+	if !j.signatureValid(header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), signature) {
+		return invalid("signature is invalid")
+	}
+
+	leeway := time.Duration(j.Leeway) * time.Second
+	if exp, ok := numericClaim(claims, "exp"); ok && !now.Before(exp.Add(leeway)) {
+		return invalid("token has expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(leeway).Before(nbf) {
+		return invalid("token is not valid yet")
+	}
+	if j.Issuer != "" && claims["iss"] != j.Issuer {
+		return invalid("issuer is not %s", j.Issuer)
+	}
+	if j.Audience != "" && !claimContains(claims["aud"], j.Audience) {
+		return invalid("audience is not %s", j.Audience)
+	}
+
+	granted := strings.Fields(claimString(claims["scope"]))
+	if scp, ok := claims["scp"].([]interface{}); ok {
+		for _, scope := range scp {
+			granted = append(granted, claimString(scope))
+		}
+	} else if scp, ok := claims["scp"].(string); ok {
+		granted = append(granted, strings.Fields(scp)...)
+	}
+	for _, scope := range j.Scopes {
+		if !containsString(granted, scope) {
+			return nil, &jwtError{code: jwtInsufficientScope, description: fmt.Sprintf("scope %s is required", scope)}
+		}
+	}
+	for name, want := range j.Claims {
+		if !claimContains(lookupClaim(claims, name), want) {
+			return nil, &jwtError{code: jwtInsufficientScope, description: fmt.Sprintf("claim %s must be %s", name, jsonString(want))}
+		}
+	}
+	return claims, nil
+}
+
+// signatureValid checks the signature with every key that suits the
+// algorithm and, when the token names one, has its key ID. Secrets are only
+// used for HS256 and public keys only for their own algorithm, so a public
+// key can never be passed off as an HMAC secret.
+func (j *JWTAuth) signatureValid(alg, kid string, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	for _, k := range j.keys {
+		if kid != "" && k.id != "" && k.id != kid {
+			continue
+		}
+		switch key := k.key.(type) {
+		case []byte:
+			if alg != "HS256" {
+				continue
+			}
+			mac := hmac.New(sha256.New, key)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if alg == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+				return true
+			}
+		case *ecdsa.PublicKey:
+			if alg != "ES256" || len(signature) != 64 {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(key, digest[:], r, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadJWKS reads the RSA, P-256 and symmetric keys of a JWKS file. Keys of
+// other types are skipped.
+func loadJWKS(path string) ([]jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	var keys []jwtKey
+	for i, jwk := range set.Keys {
+		var key interface{}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+				return nil, fmt.Errorf("key %d: invalid RSA key", i)
+			}
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if jwk.Crv != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("key %d: invalid EC key", i)
+			}
+			ecKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !ecKey.Curve.IsOnCurve(ecKey.X, ecKey.Y) {
+				return nil, fmt.Errorf("key %d: point is not on P-256", i)
+			}
+			key = ecKey
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("key %d: invalid symmetric key", i)
+			}
+			key = secret
+		default:
+			continue
+		}
+		keys = append(keys, jwtKey{id: jwk.Kid, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable keys")
+	}
+	return keys, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+// lookupClaim follows a dotted name such as realm_access.roles.
+func lookupClaim(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	var value interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[part]
+	}
+	return value
+}
+
+// claimContains reports whether the claim is the value or, for an array, has
+// it among its items. Values are compared as JSON, so 1 matches 1.0.
+func claimContains(claim, want interface{}) bool {
+	if items, ok := claim.([]interface{}); ok {
+		for _, item := range items {
+			if jsonString(item) == jsonString(want) {
+				return true
+			}
+		}
+		return false
+	}
+	return claim != nil && jsonString(claim) == jsonString(want)
+}
+
+func claimString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTClaims returns the claims of the JWT the request was let in with, or nil.
+func JWTClaims(req *http.Request) map[string]interface{} {
+	claims, _ := req.Context().Value(jwtClaimsKey).(map[string]interface{})
+	return claims
+}
+
+func withJWTClaims(req *http.Request, claims map[string]interface{}) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), jwtClaimsKey, claims))
+}
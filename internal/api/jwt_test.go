@@ -0,0 +1,283 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signJWT builds a token signed with key: a []byte secret for HS256, an
+// *rsa.PrivateKey for RS256 or an *ecdsa.PrivateKey for ES256.
+func signJWT(t *testing.T, key interface{}, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"typ": "JWT"}
+	switch key.(type) {
+	case []byte:
+		header["alg"] = "HS256"
+	case *rsa.PrivateKey:
+		header["alg"] = "RS256"
+	case *ecdsa.PrivateKey:
+		header["alg"] = "ES256"
+	}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(header) + "." + encode(claims)
+
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// writeJWKS writes the public halves of the keys to a JWKS file in dir.
+func writeJWKS(t *testing.T, dir string, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "OKP", "kid": "unsupported"},
+	}}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuthMiddleware_JWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeJWKS(t, dir, rsaKey, ecKey)
+
+	// The JWKS path is relative to the routes file, and the JWKS file in the
+	// routes directory is not taken for a routes file.
+	routesFile := filepath.Join(dir, "routes.json")
+	routes := `[
+		{"path": "/orders", "method": "GET", "status_code": 200,
+		 "auth": {"jwt": {"jwks": "jwks.json", "issuer": "https://issuer.example.com", "audience": "orders"}}},
+		{"path": "/orders", "method": "DELETE", "status_code": 204,
+		 "auth": {"jwt": {"jwks": "jwks.json", "scopes": ["orders:write"], "claims": {"realm_access.roles": "admin"}}}},
+		{"path": "/hmac", "method": "GET", "status_code": 200,
+		 "auth": {"jwt": {"secrets": ["hmac-secret"]}}}
+	]`
+	if err := os.WriteFile(routesFile, []byte(routes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter()
+	if err := router.LoadRoutesFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	middleware := &AuthMiddleware{Next: router}
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"iss": "https://issuer.example.com", "aud": []string{"orders", "billing"}, "exp": now + 60}
+	with := func(changes map[string]interface{}) map[string]interface{} {
+		claims := make(map[string]interface{})
+		for k, v := range valid {
+			claims[k] = v
+		}
+		for k, v := range changes {
+			claims[k] = v
+		}
+		return claims
+	}
+	admin := map[string]interface{}{"scope": "orders:read orders:write", "realm_access": map[string]interface{}{"roles": []string{"user", "admin"}}}
+	rsaToken := signJWT(t, rsaKey, "rsa-1", valid)
+
+	testCases := []struct {
+		desc       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+		wantError  string
+	}{
+		{desc: "RS256", method: "GET", path: "/orders", token: rsaToken, wantStatus: http.StatusOK},
+		{desc: "ES256", method: "GET", path: "/orders", token: signJWT(t, ecKey, "ec-1", valid), wantStatus: http.StatusOK},
+		{desc: "ES256 without kid", method: "GET", path: "/orders", token: signJWT(t, ecKey, "", valid), wantStatus: http.StatusOK},
+		{desc: "HS256", method: "GET", path: "/hmac", token: signJWT(t, []byte("hmac-secret"), "", nil), wantStatus: http.StatusOK},
+		{desc: "no token", method: "GET", path: "/orders", wantStatus: http.StatusUnauthorized},
+		{desc: "unknown key", method: "GET", path: "/orders", token: signJWT(t, otherKey, "", valid), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "wrong kid", method: "GET", path: "/orders", token: signJWT(t, rsaKey, "ec-1", valid), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "wrong secret", method: "GET", path: "/hmac", token: signJWT(t, []byte("guess"), "", nil), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "alg none", method: "GET", path: "/orders", token: strings.Join(strings.Split(rsaToken, ".")[:2], ".") + ".", wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "malformed", method: "GET", path: "/orders", token: "not-a-jwt", wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "expired", method: "GET", path: "/orders", token: signJWT(t, rsaKey, "", with(map[string]interface{}{"exp": now - 10})), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "not valid yet", method: "GET", path: "/orders", token: signJWT(t, rsaKey, "", with(map[string]interface{}{"nbf": now + 60})), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "wrong issuer", method: "GET", path: "/orders", token: signJWT(t, rsaKey, "", with(map[string]interface{}{"iss": "https://evil.example.com"})), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "wrong audience", method: "GET", path: "/orders", token: signJWT(t, rsaKey, "", with(map[string]interface{}{"aud": "billing"})), wantStatus: http.StatusUnauthorized, wantError: "invalid_token"},
+		{desc: "scope and claim", method: "DELETE", path: "/orders", token: signJWT(t, ecKey, "", admin), wantStatus: http.StatusNoContent},
+		{desc: "missing scope", method: "DELETE", path: "/orders", token: signJWT(t, ecKey, "", map[string]interface{}{"scp": []string{"orders:read"}}), wantStatus: http.StatusForbidden, wantError: "insufficient_scope"},
+		{desc: "wrong claim", method: "DELETE", path: "/orders", token: signJWT(t, ecKey, "", map[string]interface{}{"scp": "orders:write", "realm_access": map[string]interface{}{"roles": []string{"user"}}}), wantStatus: http.StatusForbidden, wantError: "insufficient_scope"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(tC.method, tC.path, http.NoBody)
+			if tC.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tC.token)
+			}
+			rr := httptest.NewRecorder()
+			middleware.ServeHTTP(rr, req)
+			if status := rr.Code; status != tC.wantStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, tC.wantStatus)
+			}
+			challenge := rr.Header().Get("WWW-Authenticate")
+			if tC.wantError != "" && !strings.Contains(challenge, `error="`+tC.wantError+`"`) {
+				t.Errorf("Wrong WWW-Authenticate: got %q want error %q", challenge, tC.wantError)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_JWTInsufficientScope(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/reports", Method: "GET", StatusCode: http.StatusOK, Auth: &RouteAuth{
+		JWT: &JWTAuth{Scopes: []string{"reports:read", "reports:export"}},
+	}})
+	// The route has no keys of its own, so the server's are used.
+	server := &JWTAuth{Secrets: []string{"s3cret"}}
+	if err := server.LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+	middleware := &AuthMiddleware{JWT: server, Next: router}
+
+	req := httptest.NewRequest("GET", "/reports", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+signJWT(t, []byte("s3cret"), "", map[string]interface{}{"scope": "reports:read"}))
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
+	}
+	want := `Bearer realm="faux", error="insufficient_scope", error_description="scope reports:export is required", scope="reports:read reports:export"`
+	if got := rr.Header().Get("WWW-Authenticate"); got != want {
+		t.Errorf("Wrong WWW-Authenticate: got %q want %q", got, want)
+	}
+}
+
+func TestAuthMiddleware_JWTAuthRequired(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{
+			"path": "/me",
+			"method": "GET",
+			"status_code": 200,
+			"auth_required": true,
+			"body_template": "{\"sub\": \"{{.Claims.sub}}\", \"tenant\": \"{{.Claims.org.tenant}}\"}"
+		}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	server := &JWTAuth{Secrets: []string{"s3cret"}, Audience: "faux"}
+	if err := server.LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+	middleware := &AuthMiddleware{Token: "mytoken", JWT: server, Next: router}
+
+	claims := map[string]interface{}{"sub": "ada", "aud": "faux", "org": map[string]string{"tenant": "acme"}}
+	req := httptest.NewRequest("GET", "/me", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+signJWT(t, []byte("s3cret"), "", claims))
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if got, want := rr.Body.String(), `{"sub": "ada", "tenant": "acme"}`; got != want {
+		t.Errorf("Handler returned unexpected body: got %v want %v", got, want)
+	}
+
+	// The server's token still works alongside JWTs.
+	req = httptest.NewRequest("GET", "/me", http.NoBody)
+	req.Header.Set("Authorization", "mytoken")
+	rr = httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	req = httptest.NewRequest("GET", "/me", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+signJWT(t, []byte("s3cret"), "", map[string]interface{}{"aud": "other"}))
+	rr = httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidJWT(t *testing.T) {
+	testCases := []string{
+		`[{"path": "/a", "method": "GET", "auth": {"jwt": {"secrets": [""]}}}]`,
+		`[{"path": "/a", "method": "GET", "auth": {"jwt": {"jwks": "/does/not/exist.json"}}}]`,
+		`[{"path": "/a", "method": "GET", "auth": {"jwt": {"secrets": ["s"], "leeway": -1}}}]`,
+		`[{"path": "/a", "method": "GET", "auth": {"jwt": {"scopes": ["read"]}}}]`,
+	}
+	for _, routes := range testCases {
+		if err := NewRouter().LoadRoutesFromJSON([]byte(routes)); err == nil {
+			t.Errorf("Expected an error loading %s", routes)
+		}
+	}
+
+	// Routes without keys of their own are fine once the server has some.
+	router := NewRouter()
+	router.JWTKeys = true
+	if err := router.LoadRoutesFromJSON([]byte(`[{"path": "/a", "method": "GET", "auth": {"jwt": {"scopes": ["read"]}}}]`)); err != nil {
+		t.Errorf("Failed to load routes: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Kinds of drift between routes and a spec found by LintRoutes.
+const (
+	// LintUnknownRoute is a route with no operation in the spec.
+	LintUnknownRoute = "unknown-route"
+	// LintMissingOperation is an operation no route serves.
+	LintMissingOperation = "missing-operation"
+	// LintUndeclaredStatus is a status a route answers with that its
+	// operation does not declare.
+	LintUndeclaredStatus = "undeclared-status"
+	// LintInvalidBody is a JSON response body that fails its schema.
+	LintInvalidBody = "invalid-body"
+)
+
+// LintFinding is one way the routes and the spec disagree.
+type LintFinding struct {
+	Kind   string `json:"kind"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Status is set for findings about one response of a route.
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Method, f.Path, f.Message)
+}
+
+// lintOperation is an operation of the spec and whether a route serves it.
+type lintOperation struct {
+	method    string
+	path      string
+	operation *openapi3.Operation
+	served    bool
+}
+
+// LintRoutes compares routes with a spec. It reports routes whose method and
+// path match no operation, operations no route serves, statuses the matching
+// operation does not declare, and JSON bodies that fail the schema of their
+// response. Route patterns match spec paths whatever the parameters are
+// named, and a literal segment in a route matches a parameter in the spec.
+func LintRoutes(routes []*Route, spec *openapi3.T) []LintFinding {
+	var operations []*lintOperation
+	for path, pathItem := range spec.Paths {
+		for method, operation := range pathItem.Operations() {
+			operations = append(operations, &lintOperation{method: method, path: path, operation: operation})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].path != operations[j].path {
+			return operations[i].path < operations[j].path
+		}
+		return operations[i].method < operations[j].method
+	})
+
+	var findings []LintFinding
+	for _, route := range routes {
+		method := strings.ToUpper(route.Method)
+		// A path the spec has as it is wins over one it fits as a pattern.
+		var match *lintOperation
+		for _, op := range operations {
+			if op.method != method {
+				continue
+			}
+			if op.path == route.Path {
+				match = op
+				break
+			}
+			if match == nil && lintPathMatches(route.Path, op.path) {
+				match = op
+			}
+		}
+		if match == nil {
+			findings = append(findings, LintFinding{
+				Kind:    LintUnknownRoute,
+				Method:  method,
+				Path:    route.Path,
+				Message: "no operation in the spec",
+			})
+			continue
+		}
+		match.served = true
+		if route.ProxyTo != "" || route.Proxy {
+			continue
+		}
+		findings = append(findings, lintResponses(route, match.operation)...)
+	}
+
+	for _, op := range operations {
+		if !op.served {
+			findings = append(findings, LintFinding{
+				Kind:    LintMissingOperation,
+				Method:  op.method,
+				Path:    op.path,
+				Message: "operation has no route",
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Status < b.Status
+	})
+	return findings
+}
+
+// lintPathMatches reports whether a route pattern stands for the spec path.
+func lintPathMatches(pattern, specPath string) bool {
+	patternParts := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+	specParts := strings.Split(strings.TrimSuffix(specPath, "/"), "/")
+	for i, part := range patternParts {
+		kind := segmentKind(part)
+		if kind == segCatchAll {
+			return true
+		}
+		if i >= len(specParts) {
+			return false
+		}
+		specIsParam := segmentKind(specParts[i]) == segParam
+		switch kind {
+		case segLiteral:
+			if part != specParts[i] && !specIsParam {
+				return false
+			}
+		case segParam, segWildcard:
+			if !specIsParam {
+				return false
+			}
+		}
+	}
+	return len(patternParts) == len(specParts)
+}
+
+// lintResponses checks each response the route can serve against the
+// operation's responses.
+func lintResponses(route *Route, operation *openapi3.Operation) []LintFinding {
+	var findings []LintFinding
+	check := func(status int, headers map[string]string, body responseBody) {
+		if status == 0 {
+			status = http.StatusOK
+		}
+		finding := LintFinding{Method: strings.ToUpper(route.Method), Path: route.Path, Status: status}
+
+		response := declaredResponse(operation, status)
+		if response == nil {
+			finding.Kind = LintUndeclaredStatus
+			finding.Message = fmt.Sprintf("status %d is not declared in the spec", status)
+			findings = append(findings, finding)
+			return
+		}
+		if route.BodyTemplate != "" {
+			return
+		}
+
+		value, contentType, ok := lintBody(headers, body)
+		if !ok {
+			return
+		}
+		mediaType := response.Content.Get(contentType)
+		if mediaType == nil {
+			_, mediaType = pickMediaType(response.Content)
+		}
+		if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+			return
+		}
+		if err := mediaType.Schema.Value.VisitJSON(value, openapi3.MultiErrors()); err != nil {
+			finding.Kind = LintInvalidBody
+			var reasons []string
+			for _, schemaErr := range schemaErrorsOf(err) {
+				reason := schemaErr.Reason
+				if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+					reason = "/" + strings.Join(pointer, "/") + ": " + reason
+				}
+				reasons = append(reasons, reason)
+			}
+			if len(reasons) == 0 {
+				reasons = append(reasons, err.Error())
+			}
+			finding.Message = fmt.Sprintf("%d response body does not match the schema: %s", status, strings.Join(reasons, "; "))
+			findings = append(findings, finding)
+		}
+	}
+
+	if len(route.Responses) == 0 {
+		check(route.StatusCode, route.ResponseHeaders, route.body())
+		return findings
+	}
+	for i := range route.Responses {
+		resp := &route.Responses[i]
+		status, headers, body := route.StatusCode, route.ResponseHeaders, route.body()
+		if resp.StatusCode != 0 {
+			status = resp.StatusCode
+		}
+		if len(resp.ResponseHeaders) > 0 {
+			headers = resp.ResponseHeaders
+		}
+		if b := resp.body(); !b.isEmpty() {
+			body = b
+		}
+		check(status, headers, body)
+	}
+	return findings
+}
+
+// declaredResponse returns the operation's response for status, its range
+// such as 4XX, or its default response.
+func declaredResponse(operation *openapi3.Operation, status int) *openapi3.Response {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if ref := operation.Responses[key]; ref != nil && ref.Value != nil {
+			return ref.Value
+		}
+	}
+	return nil
+}
+
+// lintBody decodes a JSON body for schema validation. It reports false for
+// bodies that are empty or not JSON.
+func lintBody(headers map[string]string, body responseBody) (interface{}, string, bool) {
+	var data []byte
+	contentType := ""
+	if body.usesFile() {
+		var err error
+		if data, err = os.ReadFile(body.File); err != nil {
+			return nil, "", false
+		}
+		contentType = mime.TypeByExtension(filepath.Ext(body.File))
+	} else {
+		var err error
+		if data, contentType, err = body.encode(); err != nil || len(data) == 0 {
+			return nil, "", false
+		}
+	}
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			contentType = value
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	if !strings.Contains(contentType, "json") {
+		return nil, "", false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, "", false
+	}
+	return value, contentType, true
+}
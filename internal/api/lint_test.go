@@ -0,0 +1,95 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockLintSpec = `
+openapi: 3.0.0
+info: {title: Users, version: "1"}
+paths:
+  /users/{id}:
+    get:
+      responses:
+        '200':
+          description: A user
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id: {type: integer}
+                  name: {type: string}
+        4XX:
+          description: Client error
+  /users/me:
+    get:
+      responses:
+        '200': {description: The current user}
+  /users:
+    post:
+      responses:
+        '201': {description: Created}
+`
+
+const mockLintRoutes = `[
+	{"path": "/users/{userId}", "method": "GET", "status_code": 200, "body_json": {"id": "7"}},
+	{"path": "/users/42", "method": "GET", "status_code": 404},
+	{"path": "/users/me", "method": "GET", "status_code": 200, "body_json": {"anything": true}},
+	{"path": "/users/{id}", "method": "DELETE", "status_code": 204},
+	{"path": "/users/{id}", "method": "GET", "status_code": 200, "match": {"query": {"fields": {"equals": "id"}}},
+	 "responses": [{"body_json": {"id": 1, "name": "Ada"}}, {"status_code": 500}]}
+]`
+
+func TestLintRoutes(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "users.json"), []byte(mockLintRoutes), 0o644))
+
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromDir(dir))
+	spec, err := ParseOpenAPISchema(mockLintSpec)
+	assert.NoError(t, err)
+
+	type finding struct {
+		Kind   string
+		Method string
+		Path   string
+		Status int
+	}
+	var got []finding
+	for _, f := range LintRoutes(router.RouteList(), spec) {
+		assert.NotEmpty(t, f.Message)
+		got = append(got, finding{f.Kind, f.Method, f.Path, f.Status})
+	}
+
+	assert.ElementsMatch(t, []finding{
+		{LintMissingOperation, "POST", "/users", 0},
+		{LintUnknownRoute, "DELETE", "/users/{id}", 0},
+		// id is a string and name is missing.
+		{LintInvalidBody, "GET", "/users/{userId}", 200},
+		{LintUndeclaredStatus, "GET", "/users/{id}", 500},
+	}, got)
+}
+
+func TestLintPathMatches(t *testing.T) {
+	testCases := []struct {
+		pattern, specPath string
+		want              bool
+	}{
+		{"/users/{userId}", "/users/{id}", true},
+		{"/users/42", "/users/{id}", true},
+		{"/users/*", "/users/{id}", true},
+		{"/users/{id}", "/users/me", false},
+		{"/users/**", "/users/{id}/posts", true},
+		{"/users", "/users/{id}", false},
+		{"/users/{id}/posts", "/users/{id}", false},
+	}
+	for _, tC := range testCases {
+		assert.Equal(t, tC.want, lintPathMatches(tC.pattern, tC.specPath), "%s against %s", tC.pattern, tC.specPath)
+	}
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RequestMatch lists the conditions a request must meet, on top of its method
+// and path, for a route to be chosen.
+type RequestMatch struct {
+	Query   map[string]ValueMatch `json:"query,omitempty"`
+	Headers map[string]ValueMatch `json:"headers,omitempty"`
+	// Body maps JSONPath expressions such as $.user.name to the value they
+	// must equal in the JSON request body.
+	Body map[string]interface{} `json:"body,omitempty"`
+}
+
+// ValueMatch is a condition on a query parameter or header. Every field that
+// is set must hold for at least one of its values.
+type ValueMatch struct {
+	Equals   string `json:"equals,omitempty"`
+	Regex    string `json:"regex,omitempty"`
+	Contains string `json:"contains,omitempty"`
+	Present  bool   `json:"present,omitempty"`
+	Absent   bool   `json:"absent,omitempty"`
+	// Values must be exactly the values sent, in any order.
+	Values []string `json:"values,omitempty"`
+}
+
+// regexCache holds compiled ValueMatch.Regex patterns, so they are compiled
+// once instead of on every request.
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+// validate checks the regular expressions and JSONPath expressions.
+func (m *RequestMatch) validate() error {
+	for _, conditions := range []map[string]ValueMatch{m.Query, m.Headers} {
+		for name, vm := range conditions {
+			if vm.Regex == "" {
+				continue
+			}
+			if _, err := compileRegex(vm.Regex); err != nil {
+				return fmt.Errorf("match %s: %w", name, err)
+			}
+		}
+	}
+	for path := range m.Body {
+		if _, err := parseJSONPath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns a description of every condition the request fails, or nil
+// if it meets them all.
+func (m *RequestMatch) check(query url.Values, header http.Header, body []byte) []string {
+	var failures []string
+
+	for name, vm := range m.Query {
+		if reason := vm.check(query[name]); reason != "" {
+			failures = append(failures, fmt.Sprintf("query %s %s", name, reason))
+		}
+	}
+	for name, vm := range m.Headers {
+		if reason := vm.check(header.Values(name)); reason != "" {
+			failures = append(failures, fmt.Sprintf("header %s %s", http.CanonicalHeaderKey(name), reason))
+		}
+	}
+
+	if len(m.Body) > 0 {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			failures = append(failures, "body is not JSON")
+		} else {
+			for path, expected := range m.Body {
+				actual, ok := evalJSONPath(doc, path)
+				if !ok {
+					failures = append(failures, fmt.Sprintf("body %s is missing", path))
+				} else if !jsonEqual(actual, expected) {
+					failures = append(failures, fmt.Sprintf("body %s is %s, not %s", path, jsonString(actual), jsonString(expected)))
+				}
+			}
+		}
+	}
+
+	// Map iteration is random; keep the report stable.
+	sort.Strings(failures)
+	return failures
+}
+
+// count returns the number of conditions, used to prefer more specific routes.
+// A values condition counts once per value, so a route expecting a and b wins
+// over one expecting only a.
+func (m *RequestMatch) count() int {
+	if m == nil {
+		return 0
+	}
+	n := len(m.Body)
+	for _, conditions := range []map[string]ValueMatch{m.Query, m.Headers} {
+		for _, vm := range conditions {
+			if len(vm.Values) > 1 {
+				n += len(vm.Values)
+			} else {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// check returns why values fail the condition, or "" if they meet it.
+func (vm ValueMatch) check(values []string) string {
+	if vm.Absent {
+		if len(values) > 0 {
+			return "is present"
+		}
+		return ""
+	}
+	if len(values) == 0 {
+		return "is missing"
+	}
+	if len(vm.Values) > 0 && !sameValues(values, vm.Values) {
+		return fmt.Sprintf("is %q, not %q", values, vm.Values)
+	}
+
+	for _, value := range values {
+		if vm.matches(value) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("is %q, which does not match", values[0])
+}
+
+// sameValues reports whether a and b hold the same values, ignoring order.
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return strings.Join(sortedValues(a), "\x00") == strings.Join(sortedValues(b), "\x00")
+}
+
+func sortedValues(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (vm ValueMatch) matches(value string) bool {
+	if vm.Equals != "" && value != vm.Equals {
+		return false
+	}
+	if vm.Contains != "" && !strings.Contains(value, vm.Contains) {
+		return false
+	}
+	if vm.Regex != "" {
+		re, err := compileRegex(vm.Regex)
+		if err != nil || !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseJSONPath splits a JSONPath expression into object keys and array
+// indexes. Only the $, .key, ['key'] and [n] forms are supported.
+func parseJSONPath(path string) ([]interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", path)
+	}
+
+	var tokens []interface{}
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("JSONPath %q has an empty key", path)
+			}
+			tokens = append(tokens, key)
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("JSONPath %q has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				tokens = append(tokens, inner[1:len(inner)-1])
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("JSONPath %q has an invalid index %q", path, inner)
+				}
+				tokens = append(tokens, index)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("JSONPath %q is invalid at %q", path, rest)
+		}
+	}
+	return tokens, nil
+}
+
+// evalJSONPath returns the value at path in a decoded JSON document.
+func evalJSONPath(doc interface{}, path string) (interface{}, bool) {
+	tokens, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	current := doc
+	for _, token := range tokens {
+		switch t := token.(type) {
+		case string:
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = obj[t]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := current.([]interface{})
+			if !ok || t < 0 || t >= len(arr) {
+				return nil, false
+			}
+			current = arr[t]
+		}
+	}
+	return current, true
+}
+
+// jsonEqual compares two values by their JSON encoding, so that numbers
+// decoded as float64 equal the same numbers written as ints.
+func jsonEqual(a, b interface{}) bool {
+	return jsonString(a) == jsonString(b)
+}
+
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeHTTP_Match(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{
+			"path": "/login",
+			"method": "POST",
+			"status_code": 401
+		},
+		{
+			"path": "/login",
+			"method": "POST",
+			"status_code": 200,
+			"match": {"body": {"$.user.name": "alice"}}
+		},
+		{
+			"path": "/login",
+			"method": "POST",
+			"status_code": 423,
+			"priority": 10,
+			"match": {"headers": {"X-Locked": {"present": true}}}
+		}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if len(router.Routes) != 3 {
+		t.Fatalf("Expected three routes, got %v", len(router.Routes))
+	}
+
+	testCases := []struct {
+		desc     string
+		body     string
+		locked   bool
+		expected int
+	}{
+		{"Matching body", `{"user": {"name": "alice"}}`, false, http.StatusOK},
+		{"Other body", `{"user": {"name": "bob"}}`, false, http.StatusUnauthorized},
+		{"Higher priority wins", `{"user": {"name": "alice"}}`, true, http.StatusLocked},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/login", strings.NewReader(tC.body))
+			if tC.locked {
+				req.Header.Set("X-Locked", "1")
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tC.expected {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tC.expected)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_MatchFailsWithOtherMethods(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{"path": "/login", "method": "GET", "status_code": 200, "match": {"query": {"user": {"equals": "alice"}}}},
+		{"path": "/login", "method": "POST", "status_code": 201},
+		{"path": "/start", "method": "GET", "status_code": 200, "scenario": "flow", "required_state": "Done"},
+		{"path": "/start", "method": "POST", "status_code": 201}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	// GET is allowed on both paths, so failing conditions are a 404, not a 405.
+	for _, target := range []string{"/login?user=bob", "/start"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", target, http.NoBody))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: got status %v want %v", target, rr.Code, http.StatusNotFound)
+		}
+		if allow := rr.Header().Get("Allow"); allow != "" {
+			t.Errorf("%s: unexpected Allow header %v", target, allow)
+		}
+	}
+}
+
+func TestServeHTTP_MatchSlowBody(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{"path": "/upload", "method": "POST", "status_code": 200, "match": {"body": {"$.kind": "photo"}}}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	body, upload := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", body))
+	}()
+
+	// Changing the routes must not wait for the stalled upload.
+	time.Sleep(50 * time.Millisecond)
+	added := make(chan struct{})
+	go func() {
+		router.AddRoute(&Route{Path: "/other", Method: "GET", StatusCode: http.StatusOK})
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Errorf("AddRoute blocked by a request body that is still being read")
+	}
+	upload.Close()
+	<-done
+}
+
+func TestRequestMatchCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc     string
+		match    RequestMatch
+		query    string
+		header   http.Header
+		body     string
+		expected []string
+	}{
+		{
+			desc:  "Query equals",
+			match: RequestMatch{Query: map[string]ValueMatch{"page": {Equals: "2"}}},
+			query: "page=2",
+		},
+		{
+			desc:     "Query regex fails",
+			match:    RequestMatch{Query: map[string]ValueMatch{"page": {Regex: `^\d+$`}}},
+			query:    "page=two",
+			expected: []string{`query page is "two", which does not match`},
+		},
+		{
+			desc:  "Query values in any order",
+			match: RequestMatch{Query: map[string]ValueMatch{"tag": {Values: []string{"a", "b"}}}},
+			query: "tag=b&tag=a",
+		},
+		{
+			desc:     "Query values differ",
+			match:    RequestMatch{Query: map[string]ValueMatch{"tag": {Values: []string{"a", "b"}}}},
+			query:    "tag=a",
+			expected: []string{`query tag is ["a"], not ["a" "b"]`},
+		},
+		{
+			desc:   "Header contains",
+			match:  RequestMatch{Headers: map[string]ValueMatch{"accept": {Contains: "json"}}},
+			header: http.Header{"Accept": {"application/json"}},
+		},
+		{
+			desc:     "Header absent",
+			match:    RequestMatch{Headers: map[string]ValueMatch{"X-Debug": {Absent: true}}},
+			header:   http.Header{"X-Debug": {"1"}},
+			expected: []string{"header X-Debug is present"},
+		},
+		{
+			desc:     "Header missing",
+			match:    RequestMatch{Headers: map[string]ValueMatch{"X-Debug": {Present: true}}},
+			expected: []string{"header X-Debug is missing"},
+		},
+		{
+			desc:  "Body array index",
+			match: RequestMatch{Body: map[string]interface{}{"$.items[1].qty": 2}},
+			body:  `{"items": [{"qty": 1}, {"qty": 2}]}`,
+		},
+		{
+			desc:     "Body mismatch",
+			match:    RequestMatch{Body: map[string]interface{}{"$['user'].name": "alice"}},
+			body:     `{"user": {"name": "bob"}}`,
+			expected: []string{`body $['user'].name is "bob", not "alice"`},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			query, _ := url.ParseQuery(tC.query)
+			header := tC.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			failures := tC.match.check(query, header, []byte(tC.body))
+			if !reflect.DeepEqual(failures, tC.expected) {
+				t.Errorf("Expected failures %v, but got %v", tC.expected, failures)
+			}
+		})
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidMatch(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "status_code": 200, "match": {"query": {"q": {"regex": "("}}}}]`))
+	if err == nil {
+		t.Errorf("Expected error for invalid regex")
+	}
+
+	err = router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "status_code": 200, "match": {"body": {"user.name": "x"}}}]`))
+	if err == nil {
+		t.Errorf("Expected error for invalid JSONPath")
+	}
+}
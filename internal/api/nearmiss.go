@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxRouteMisses caps how many routes a 404 lists.
+const maxRouteMisses = 3
+
+// routeMiss is a route that did not match a request, and why.
+type routeMiss struct {
+	route    *Route
+	failures []string
+	distance int
+}
+
+// nearestRoutes ranks the configured routes by how close they came to
+// matching the request: fewest failed criteria first, then the most similar
+// path.
+func (r *Router) nearestRoutes(req *http.Request) []routeMiss {
+	body, _ := readBody(req)
+
+	var misses []routeMiss
+	for _, route := range r.RouteList() {
+		misses = append(misses, routeMiss{
+			route:    route,
+			failures: r.explainMiss(route, req, body),
+			distance: levenshtein(route.Path, req.URL.Path),
+		})
+	}
+
+	sort.SliceStable(misses, func(i, j int) bool {
+		if len(misses[i].failures) != len(misses[j].failures) {
+			return len(misses[i].failures) < len(misses[j].failures)
+		}
+		return misses[i].distance < misses[j].distance
+	})
+	if len(misses) > maxRouteMisses {
+		misses = misses[:maxRouteMisses]
+	}
+	return misses
+}
+
+// explainMiss lists every criterion of the route the request fails.
+func (r *Router) explainMiss(route *Route, req *http.Request, body []byte) []string {
+	var failures []string
+
+	if !strings.EqualFold(route.Method, req.Method) {
+		failures = append(failures, fmt.Sprintf("method is %s, not %s", req.Method, strings.ToUpper(route.Method)))
+	}
+
+	path := req.URL.Path
+	if _, ok := matchPath(route.Path, path); !ok {
+		_, withSlash := matchPath(route.Path, path+"/")
+		_, withoutSlash := matchPath(route.Path, strings.TrimSuffix(path, "/"))
+		if withSlash || (withoutSlash && strings.HasSuffix(path, "/")) {
+			failures = append(failures, fmt.Sprintf("path %s differs from %s by a trailing slash", path, route.Path))
+		} else {
+			failures = append(failures, fmt.Sprintf("path %s does not match %s", path, route.Path))
+		}
+	}
+
+	if route.Match != nil {
+		failures = append(failures, route.Match.check(req.URL.Query(), req.Header, body)...)
+	}
+	if !r.inRequiredState(route) {
+		failures = append(failures, fmt.Sprintf("scenario %s is in state %s, not %s", route.Scenario, r.scenarioState(route.Scenario), route.RequiredState))
+	}
+	return failures
+}
+
+// notFound answers a request no route matched. Unless the router is set to
+// send plain 404s, the body and the log list the closest routes.
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	if r.PlainNotFound {
+		http.NotFound(w, req)
+		return
+	}
+
+	misses := r.nearestRoutes(req)
+	if len(misses) == 0 {
+		http.NotFound(w, req)
+		return
+	}
+
+	var report strings.Builder
+	report.WriteString("404 page not found\n\nClosest routes:\n")
+	summaries := make([]string, 0, len(misses))
+	for _, miss := range misses {
+		summary := fmt.Sprintf("%s: %s", routeKey(miss.route.Method, miss.route.Path), strings.Join(miss.failures, "; "))
+		summaries = append(summaries, summary)
+		fmt.Fprintf(&report, "  %s\n", summary)
+	}
+
+	log.Printf("No route for %s %s. Closest: %s", req.Method, req.URL.Path, strings.Join(summaries, " | "))
+	http.Error(w, strings.TrimSuffix(report.String(), "\n"), http.StatusNotFound)
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	lowest := values[0]
+	for _, v := range values[1:] {
+		if v < lowest {
+			lowest = v
+		}
+	}
+	return lowest
+}
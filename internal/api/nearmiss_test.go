@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeHTTP_NearMisses(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/users", Method: "GET", StatusCode: http.StatusOK})
+	router.AddRoute(&Route{
+		Path:       "/orders",
+		Method:     "POST",
+		StatusCode: http.StatusCreated,
+		Match:      &RequestMatch{Headers: map[string]ValueMatch{"X-Tenant": {Equals: "a"}}},
+	})
+	router.AddRoute(&Route{Path: "/health", Method: "GET", StatusCode: http.StatusOK})
+
+	testCases := []struct {
+		desc     string
+		method   string
+		path     string
+		expected string
+	}{
+		{"Trailing slash", "GET", "/users/", "GET /users: path /users/ differs from /users by a trailing slash"},
+		{"Header mismatch", "POST", "/orders", "POST /orders: header X-Tenant is missing"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tC.method, tC.path, http.NoBody))
+
+			if rr.Code != http.StatusNotFound {
+				t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+			}
+			lines := strings.Split(rr.Body.String(), "\n")
+			if len(lines) < 4 || strings.TrimSpace(lines[3]) != tC.expected {
+				t.Errorf("Expected closest route %q, got body:\n%s", tC.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeHTTP_PlainNotFound(t *testing.T) {
+	router := NewRouter()
+	router.PlainNotFound = true
+	router.AddRoute(&Route{Path: "/users", Method: "GET", StatusCode: http.StatusOK})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/", http.NoBody))
+
+	if rr.Body.String() != "404 page not found\n" {
+		t.Errorf("Expected a plain 404, got %q", rr.Body.String())
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"/users", "/users/", 1},
+		{"/users", "/user", 1},
+		{"/orders", "/users", 3},
+	}
+
+	for _, tC := range testCases {
+		if got := levenshtein(tC.a, tC.b); got != tC.expected {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tC.a, tC.b, got, tC.expected)
+		}
+	}
+}
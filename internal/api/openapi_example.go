@@ -0,0 +1,181 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxExampleDepth stops example generation in deeply nested or recursive
+// schemas. Past it only required properties are filled in.
+const maxExampleDepth = 6
+
+// sampleStrings are the values generated for well-known string formats.
+var sampleStrings = map[string]string{
+	"date":      "2024-01-01",
+	"date-time": "2024-01-01T00:00:00Z",
+	"time":      "00:00:00",
+	"email":     "user@example.com",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"byte":      "ZXhhbXBsZQ==",
+}
+
+// exampleFromSchema builds a value that satisfies the schema. Examples and
+// defaults in the schema are used as they are; otherwise the value follows
+// the type, format, enum and bounds. oneOf and anyOf take their first
+// alternative and allOf merges its parts.
+func exampleFromSchema(ref *openapi3.SchemaRef) interface{} {
+	return generateExample(ref, 0, make(map[string]bool))
+}
+
+func generateExample(ref *openapi3.SchemaRef, depth int, visiting map[string]bool) interface{} {
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+	// A schema that refers back to itself is cut off rather than expanded
+	// forever.
+	if ref.Ref != "" {
+		if visiting[ref.Ref] {
+			return nil
+		}
+		visiting[ref.Ref] = true
+		defer delete(visiting, ref.Ref)
+	}
+
+	schema := ref.Value
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.OneOf) > 0:
+		return generateExample(schema.OneOf[0], depth, visiting)
+	case len(schema.AnyOf) > 0:
+		return generateExample(schema.AnyOf[0], depth, visiting)
+	case len(schema.AllOf) > 0:
+		merged := make(map[string]interface{})
+		for _, part := range schema.AllOf {
+			value := generateExample(part, depth, visiting)
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return value
+			}
+			for name, v := range obj {
+				merged[name] = v
+			}
+		}
+		return merged
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		obj := make(map[string]interface{})
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if depth >= maxExampleDepth && !isRequired(schema, name) {
+				continue
+			}
+			if value := generateExample(schema.Properties[name], depth+1, visiting); value != nil || isRequired(schema, name) {
+				obj[name] = value
+			}
+		}
+		return obj
+	case "array":
+		count := 1
+		if schema.MinItems > 1 {
+			count = int(schema.MinItems)
+		}
+		if schema.MaxItems != nil && *schema.MaxItems < uint64(count) {
+			count = int(*schema.MaxItems)
+		}
+		if depth >= maxExampleDepth && schema.MinItems == 0 {
+			count = 0
+		}
+		items := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			items = append(items, generateExample(schema.Items, depth+1, visiting))
+		}
+		return items
+	case "string":
+		return exampleString(schema)
+	case "integer":
+		return int64(exampleNumber(schema, 1))
+	case "number":
+		return exampleNumber(schema, 0.5)
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// schemaType returns the schema's type, inferring object and array from
+// properties and items when it is missing.
+func schemaType(schema *openapi3.Schema) string {
+	switch {
+	case schema.Type != "":
+		return schema.Type
+	case len(schema.Properties) > 0:
+		return "object"
+	case schema.Items != nil:
+		return "array"
+	}
+	return ""
+}
+
+func isRequired(schema *openapi3.Schema, name string) bool {
+	for _, required := range schema.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+func exampleString(schema *openapi3.Schema) string {
+	value, ok := sampleStrings[schema.Format]
+	if !ok {
+		value = "string"
+	}
+	if uint64(len(value)) < schema.MinLength {
+		value += strings.Repeat("x", int(schema.MinLength)-len(value))
+	}
+	if schema.MaxLength != nil && uint64(len(value)) > *schema.MaxLength {
+		value = value[:*schema.MaxLength]
+	}
+	return value
+}
+
+// exampleNumber returns 0 when it is allowed, otherwise the value closest to
+// it within the bounds. step is how far an exclusive bound is stepped over.
+func exampleNumber(schema *openapi3.Schema, step float64) float64 {
+	value := 0.0
+	if schema.Min != nil && value <= *schema.Min {
+		value = *schema.Min
+		if schema.ExclusiveMin {
+			value += step
+		}
+	}
+	if schema.Max != nil && value >= *schema.Max {
+		value = *schema.Max
+		if schema.ExclusiveMax {
+			value -= step
+		}
+	}
+	if schema.Type == "integer" {
+		value = math.Ceil(value)
+	}
+	return value
+}
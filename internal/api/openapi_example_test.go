@@ -0,0 +1,64 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExampleFromSchema(t *testing.T) {
+	doc, err := ParseOpenAPIFromText(`
+openapi: 3.0.0
+info: {title: Examples, version: "1"}
+paths: {}
+components:
+  schemas:
+    Node:
+      type: object
+      required: [id]
+      properties:
+        id: {type: integer, minimum: 1}
+        child: {$ref: '#/components/schemas/Node'}
+    Pet:
+      oneOf:
+        - {$ref: '#/components/schemas/Cat'}
+        - {type: string}
+    Cat:
+      type: object
+      properties:
+        name: {type: string, example: Tom}
+    Named:
+      allOf:
+        - {$ref: '#/components/schemas/Cat'}
+        - type: object
+          properties:
+            age: {type: integer, maximum: 20, minimum: 3, exclusiveMinimum: true}
+`)
+	assert.NoError(t, err)
+	schemas := doc.Components.Schemas
+
+	maxLength := uint64(3)
+	testCases := []struct {
+		desc     string
+		schema   *openapi3.SchemaRef
+		expected interface{}
+	}{
+		{desc: "enum", schema: openapi3.NewSchemaRef("", &openapi3.Schema{Type: "string", Enum: []interface{}{"b", "a"}}), expected: "b"},
+		{desc: "date-time", schema: openapi3.NewSchemaRef("", openapi3.NewDateTimeSchema()), expected: "2024-01-01T00:00:00Z"},
+		{desc: "email", schema: openapi3.NewSchemaRef("", &openapi3.Schema{Type: "string", Format: "email"}), expected: "user@example.com"},
+		{desc: "min length", schema: openapi3.NewSchemaRef("", &openapi3.Schema{Type: "string", MinLength: 8}), expected: "stringxx"},
+		{desc: "max length", schema: openapi3.NewSchemaRef("", &openapi3.Schema{Type: "string", MaxLength: &maxLength}), expected: "str"},
+		{desc: "number minimum", schema: openapi3.NewSchemaRef("", openapi3.NewFloat64Schema().WithMin(2.5)), expected: 2.5},
+		{desc: "boolean", schema: openapi3.NewSchemaRef("", openapi3.NewBoolSchema()), expected: true},
+		{desc: "array", schema: openapi3.NewSchemaRef("", openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()).WithMinItems(2)), expected: []interface{}{"string", "string"}},
+		{desc: "recursive ref", schema: schemas["Node"], expected: map[string]interface{}{"id": int64(1), "child": map[string]interface{}{"id": int64(1)}}},
+		{desc: "oneOf", schema: schemas["Pet"], expected: map[string]interface{}{"name": "Tom"}},
+		{desc: "allOf", schema: schemas["Named"], expected: map[string]interface{}{"name": "Tom", "age": int64(4)}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, exampleFromSchema(tC.schema))
+		})
+	}
+}
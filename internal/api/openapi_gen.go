@@ -3,42 +3,108 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
+// openAPISecurityScheme names the scheme routes with AuthRequired and no
+// credentials of their own use.
+const openAPISecurityScheme = "token"
+
 type OpenAPISpec struct {
-	OpenAPI string                     `json:"openapi"`
-	Info    OpenAPIInfo                `json:"info"`
-	Paths   map[string]OpenAPIPathItem `json:"paths"`
+	OpenAPI    string                     `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo                `json:"info" yaml:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths" yaml:"paths"`
+	Components *OpenAPIComponents         `json:"components,omitempty" yaml:"components,omitempty"`
 }
 
 type OpenAPIInfo struct {
-	Title   string `json:"title"`
-	Version string `json:"version"`
+	Title   string `json:"title" yaml:"title"`
+	Version string `json:"version" yaml:"version"`
 }
 
 type OpenAPIPathItem struct {
-	Get    *OpenAPIOperation `json:"get,omitempty"`
-	Post   *OpenAPIOperation `json:"post,omitempty"`
-	Put    *OpenAPIOperation `json:"put,omitempty"`
-	Delete *OpenAPIOperation `json:"delete,omitempty"`
+	Parameters []OpenAPIParameter `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Get        *OpenAPIOperation  `json:"get,omitempty" yaml:"get,omitempty"`
+	Put        *OpenAPIOperation  `json:"put,omitempty" yaml:"put,omitempty"`
+	Post       *OpenAPIOperation  `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete     *OpenAPIOperation  `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Options    *OpenAPIOperation  `json:"options,omitempty" yaml:"options,omitempty"`
+	Head       *OpenAPIOperation  `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch      *OpenAPIOperation  `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Trace      *OpenAPIOperation  `json:"trace,omitempty" yaml:"trace,omitempty"`
 }
 
 type OpenAPIOperation struct {
-	Summary     string                     `json:"summary,omitempty"`
-	Description string                     `json:"description,omitempty"`
-	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Summary     string                     `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string                     `json:"description,omitempty" yaml:"description,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Security    []map[string][]string      `json:"security,omitempty" yaml:"security,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses" yaml:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name" yaml:"name"`
+	In          string         `json:"in" yaml:"in"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool           `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Example     interface{}    `json:"example,omitempty" yaml:"example,omitempty"`
 }
 
 type OpenAPIResponse struct {
-	Description string `json:"description"`
+	Description string                      `json:"description" yaml:"description"`
+	Headers     map[string]OpenAPIHeader    `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type OpenAPIHeader struct {
+	Schema  *OpenAPISchema `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Example interface{}    `json:"example,omitempty" yaml:"example,omitempty"`
+}
+
+// OpenAPIMediaType holds the bodies a response is served with: one as its
+// example, or several as named examples.
+type OpenAPIMediaType struct {
+	Example  interface{}               `json:"example,omitempty" yaml:"example,omitempty"`
+	Examples map[string]OpenAPIExample `json:"examples,omitempty" yaml:"examples,omitempty"`
+}
+
+type OpenAPIExample struct {
+	Value interface{} `json:"value" yaml:"value"`
+}
+
+type OpenAPISchema struct {
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
 }
 
+type OpenAPISecurityScheme struct {
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	In          string `json:"in,omitempty" yaml:"in,omitempty"`
+	Scheme      string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	// BearerFormat hints at what bearer tokens are, such as JWT.
+	BearerFormat string `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+}
+
+// GenerateOpenAPI describes the configured routes as an OpenAPI 3.1 document.
+// Routes that share a method and path, such as variants with different match
+// conditions, are merged into one operation, and the bodies they serve become
+// the examples of its responses.
 func (r *Router) GenerateOpenAPI() OpenAPISpec {
 	spec := OpenAPISpec{
-		OpenAPI: "3.0.0",
+		OpenAPI: "3.1.0",
 		Info: OpenAPIInfo{
 			Title:   "Magic Mock API",
 			Version: "1.0",
@@ -46,34 +112,46 @@ func (r *Router) GenerateOpenAPI() OpenAPISpec {
 		Paths: make(map[string]OpenAPIPathItem),
 	}
 
-	for _, route := range r.Routes {
-		operation := OpenAPIOperation{
-			Summary:     "Auto-generated mock route",
-			Description: fmt.Sprintf("Handles %s requests for %s", route.Method, route.Path),
-			Responses: map[string]OpenAPIResponse{
-				strconv.Itoa(route.StatusCode): {
-					Description: http.StatusText(route.StatusCode),
-				},
-			},
+	// RouteList is sorted by key, so variants of an operation are adjacent.
+	operations := make(map[string]*OpenAPIOperation)
+	for _, route := range r.RouteList() {
+		path, parameters := openAPIPath(route.Path)
+		key := routeKey(route.Method, path)
+		operation, ok := operations[key]
+		if !ok {
+			operation = &OpenAPIOperation{
+				Summary:     "Auto-generated mock route",
+				Description: fmt.Sprintf("Handles %s requests for %s", route.Method, route.Path),
+				Responses:   make(map[string]OpenAPIResponse),
+			}
+			pathItem := spec.Paths[path]
+			if !pathItem.setOperation(route.Method, operation) {
+				// OpenAPI has no place for methods outside the standard ones.
+				continue
+			}
+			pathItem.Parameters = parameters
+			spec.Paths[path] = pathItem
+			operations[key] = operation
 		}
+		schemes := r.routeSecuritySchemes(route)
+		addRouteToOperation(operation, route, schemes)
 
-		pathItem := OpenAPIPathItem{}
-		switch strings.ToLower(route.Method) {
-		case "get":
-			pathItem.Get = &operation
-		case "post":
-			pathItem.Post = &operation
-		case "put":
-			pathItem.Put = &operation
-		case "delete":
-			pathItem.Delete = &operation
+		for name, scheme := range schemes {
+			if spec.Components == nil {
+				spec.Components = &OpenAPIComponents{SecuritySchemes: make(map[string]OpenAPISecurityScheme)}
+			}
+			spec.Components.SecuritySchemes[name] = scheme
 		}
-
-		spec.Paths[route.Path] = pathItem
 	}
 
 	// Add MagicRoute specifics
 	spec.Paths["/status/{statusCode}"] = OpenAPIPathItem{
+		Parameters: []OpenAPIParameter{{
+			Name:     "statusCode",
+			In:       "path",
+			Required: true,
+			Schema:   &OpenAPISchema{Type: "integer"},
+		}},
 		Get: &OpenAPIOperation{
 			Summary:     "MagicRoute for dynamic responses",
 			Description: "Generates a response dynamically based on request content. The status code can be any value between 100 and 599.",
@@ -88,9 +166,342 @@ func (r *Router) GenerateOpenAPI() OpenAPISpec {
 	return spec
 }
 
+// setOperation puts the operation under its method, reporting false for a
+// method OpenAPI cannot describe.
+func (p *OpenAPIPathItem) setOperation(method string, operation *OpenAPIOperation) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		p.Get = operation
+	case http.MethodPut:
+		p.Put = operation
+	case http.MethodPost:
+		p.Post = operation
+	case http.MethodDelete:
+		p.Delete = operation
+	case http.MethodOptions:
+		p.Options = operation
+	case http.MethodHead:
+		p.Head = operation
+	case http.MethodPatch:
+		p.Patch = operation
+	case http.MethodTrace:
+		p.Trace = operation
+	default:
+		return false
+	}
+	return true
+}
+
+// openAPIPath turns a route pattern into an OpenAPI path and its parameters.
+// Wildcards have no OpenAPI equivalent, so * and ** become parameters named
+// wildcard and path.
+func openAPIPath(pattern string) (string, []OpenAPIParameter) {
+	parts := strings.Split(pattern, "/")
+	var parameters []OpenAPIParameter
+	wildcards := 0
+	for i, part := range parts {
+		var parameter OpenAPIParameter
+		switch segmentKind(part) {
+		case segParam:
+			parameter = OpenAPIParameter{Name: part[1 : len(part)-1]}
+		case segWildcard:
+			wildcards++
+			parameter = OpenAPIParameter{Name: "wildcard", Description: "Any single path segment."}
+			if wildcards > 1 {
+				parameter.Name += strconv.Itoa(wildcards)
+			}
+		case segCatchAll:
+			parameter = OpenAPIParameter{Name: "path", Description: "The rest of the path, which may contain slashes."}
+		default:
+			continue
+		}
+		parameter.In = "path"
+		parameter.Required = true
+		parameter.Schema = &OpenAPISchema{Type: "string"}
+		parts[i] = "{" + parameter.Name + "}"
+		parameters = append(parameters, parameter)
+	}
+	return strings.Join(parts, "/"), parameters
+}
+
+// addRouteToOperation adds what a route serves to its operation: a response
+// per status it can answer with, the query and header values it matches on,
+// and the responses its auth and rate limit add.
+func addRouteToOperation(operation *OpenAPIOperation, route *Route, schemes map[string]OpenAPISecurityScheme) {
+	if route.Match != nil {
+		operation.Parameters = addMatchParameters(operation.Parameters, "query", route.Match.Query)
+		operation.Parameters = addMatchParameters(operation.Parameters, "header", route.Match.Headers)
+	}
+
+	switch {
+	case route.ProxyTo != "" || route.Proxy:
+		operation.Responses["default"] = OpenAPIResponse{Description: "Forwarded to the upstream server."}
+	case len(route.Responses) > 0:
+		for i := range route.Responses {
+			addOpenAPIResponse(operation, route, &route.Responses[i])
+		}
+	default:
+		addOpenAPIResponse(operation, route, nil)
+	}
+
+	if len(schemes) > 0 {
+		// Each scheme is an alternative, since any one credential will do.
+		names := make([]string, 0, len(schemes))
+		for name := range schemes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		operation.Security = nil
+		for _, name := range names {
+			operation.Security = append(operation.Security, map[string][]string{name: {}})
+		}
+		operation.Responses[strconv.Itoa(http.StatusUnauthorized)] = OpenAPIResponse{Description: http.StatusText(http.StatusUnauthorized)}
+	}
+	if route.RateLimitPerMin > 0 {
+		operation.Responses[strconv.Itoa(http.StatusTooManyRequests)] = OpenAPIResponse{Description: http.StatusText(http.StatusTooManyRequests)}
+	}
+}
+
+// routeSecuritySchemes returns the security schemes the route accepts, keyed
+// by the name they get in the components. A route with AuthRequired only has
+// them when the server has a token or JWT keys to enforce it with.
+func (r *Router) routeSecuritySchemes(route *Route) map[string]OpenAPISecurityScheme {
+	auth := route.Auth
+	if auth == nil {
+		if !route.AuthRequired {
+			return nil
+		}
+		schemes := make(map[string]OpenAPISecurityScheme)
+		if r.TokenAuth {
+			schemes[openAPISecurityScheme] = OpenAPISecurityScheme{
+				Type:        "apiKey",
+				Description: "The server's token in the Authorization header, bare or as a bearer token.",
+				Name:        "Authorization",
+				In:          "header",
+			}
+		}
+		if r.JWTKeys {
+			schemes["jwtAuth"] = OpenAPISecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+		}
+		return schemes
+	}
+
+	schemes := make(map[string]OpenAPISecurityScheme)
+	// Bearer tokens, JWTs and basic credentials in another header than
+	// Authorization can only be described as an API key.
+	if header := auth.header(); header != defaultAuthHeader && (len(auth.Bearer) > 0 || len(auth.Basic) > 0 || auth.JWT != nil) {
+		schemes["header_"+securitySchemeName(header)] = OpenAPISecurityScheme{
+			Type:        "apiKey",
+			Description: "A bearer token or basic credentials.",
+			Name:        header,
+			In:          "header",
+		}
+	} else {
+		if len(auth.Bearer) > 0 {
+			schemes["bearerAuth"] = OpenAPISecurityScheme{Type: "http", Scheme: "bearer"}
+		}
+		if auth.JWT != nil {
+			schemes["jwtAuth"] = OpenAPISecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+		}
+		if len(auth.Basic) > 0 {
+			schemes["basicAuth"] = OpenAPISecurityScheme{Type: "http", Scheme: "basic"}
+		}
+	}
+	for _, key := range auth.APIKeys {
+		in, name := "header", key.header()
+		if key.Query != "" {
+			in, name = "query", key.Query
+		}
+		schemes[in+"_"+securitySchemeName(name)] = OpenAPISecurityScheme{Type: "apiKey", Name: name, In: in}
+	}
+	return schemes
+}
+
+// securitySchemeName keeps the characters allowed in component names.
+func securitySchemeName(name string) string {
+	return strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '-', c == '_':
+			return c
+		}
+		return '_'
+	}, name)
+}
+
+// addOpenAPIResponse adds one response of the route, or the route's own when
+// resp is nil. Unset fields of resp fall back to the route's, as they do when
+// it is served.
+func addOpenAPIResponse(operation *OpenAPIOperation, route *Route, resp *RouteResponse) {
+	status, headers, body := route.StatusCode, route.ResponseHeaders, route.body()
+	if resp != nil {
+		if resp.StatusCode != 0 {
+			status = resp.StatusCode
+		}
+		if len(resp.ResponseHeaders) > 0 {
+			headers = resp.ResponseHeaders
+		}
+		if b := resp.body(); !b.isEmpty() {
+			body = b
+		}
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	code := strconv.Itoa(status)
+	response, ok := operation.Responses[code]
+	if !ok {
+		response = OpenAPIResponse{Description: http.StatusText(status)}
+		if response.Description == "" {
+			response.Description = "Status " + code
+		}
+	}
+
+	contentType := ""
+	for name, value := range headers {
+		if strings.EqualFold(name, "Content-Type") {
+			contentType = value
+			continue
+		}
+		if response.Headers == nil {
+			response.Headers = make(map[string]OpenAPIHeader)
+		}
+		response.Headers[name] = OpenAPIHeader{Schema: &OpenAPISchema{Type: "string"}, Example: value}
+	}
+	for name := range route.HeaderTemplates {
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		if response.Headers == nil {
+			response.Headers = make(map[string]OpenAPIHeader)
+		}
+		response.Headers[name] = OpenAPIHeader{Schema: &OpenAPISchema{Type: "string"}}
+	}
+
+	bodyContentType, example, hasExample := openAPIExample(route, body)
+	if contentType == "" {
+		contentType = bodyContentType
+	}
+	if contentType != "" {
+		if response.Content == nil {
+			response.Content = make(map[string]OpenAPIMediaType)
+		}
+		mediaType := response.Content[contentType]
+		if hasExample {
+			mediaType.addExample(example)
+		}
+		response.Content[contentType] = mediaType
+	}
+
+	operation.Responses[code] = response
+}
+
+// openAPIExample returns the content type a body is served with and, for
+// JSON and text, the body itself as an example. Bodies rendered from a
+// template, streamed from a file or binary have no example.
+func openAPIExample(route *Route, body responseBody) (string, interface{}, bool) {
+	if route.BodyTemplate != "" {
+		return "text/plain", nil, false
+	}
+	if body.usesFile() {
+		contentType := mime.TypeByExtension(filepath.Ext(body.File))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		return contentType, nil, false
+	}
+	if body.isEmpty() {
+		return "", nil, false
+	}
+
+	data, contentType, err := body.encode()
+	if err != nil {
+		return "", nil, false
+	}
+	if contentType == "application/json" {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err == nil {
+			return contentType, value, true
+		}
+	}
+	if strings.HasPrefix(contentType, "text/") {
+		return contentType, string(data), true
+	}
+	return contentType, nil, false
+}
+
+// addExample keeps a single body as the example and switches to named
+// examples once a second, different one is added.
+func (m *OpenAPIMediaType) addExample(value interface{}) {
+	switch {
+	case m.Examples != nil:
+		for _, existing := range m.Examples {
+			if jsonString(existing.Value) == jsonString(value) {
+				return
+			}
+		}
+		m.Examples[fmt.Sprintf("example%d", len(m.Examples)+1)] = OpenAPIExample{Value: value}
+	case m.Example == nil:
+		m.Example = value
+	case jsonString(m.Example) != jsonString(value):
+		m.Examples = map[string]OpenAPIExample{
+			"example1": {Value: m.Example},
+			"example2": {Value: value},
+		}
+		m.Example = nil
+	}
+}
+
+// addMatchParameters documents the query parameters or headers a route
+// matches on. They are optional, since a request without them can still
+// reach another variant of the operation.
+func addMatchParameters(parameters []OpenAPIParameter, in string, conditions map[string]ValueMatch) []OpenAPIParameter {
+	names := make([]string, 0, len(conditions))
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		condition := conditions[name]
+		if condition.Absent {
+			continue
+		}
+		exists := false
+		for _, parameter := range parameters {
+			if parameter.In == in && strings.EqualFold(parameter.Name, name) {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		parameter := OpenAPIParameter{Name: name, In: in, Schema: &OpenAPISchema{Type: "string"}}
+		if condition.Equals != "" {
+			parameter.Example = condition.Equals
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// OpenAPIHandler serves the generated document as JSON, or as YAML when the
+// path ends in .yaml or .yml.
 func (r *Router) OpenAPIHandler(w http.ResponseWriter, req *http.Request) {
 	openAPISpec := r.GenerateOpenAPI()
 
+	if strings.HasSuffix(req.URL.Path, ".yaml") || strings.HasSuffix(req.URL.Path, ".yml") {
+		data, err := yaml.Marshal(openAPISpec)
+		if err != nil {
+			http.Error(w, "Error encoding OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(data)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(openAPISpec)
 }
@@ -1,8 +1,14 @@
 package api
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestGenerateOpenAPI(t *testing.T) {
@@ -21,7 +27,7 @@ func TestGenerateOpenAPI(t *testing.T) {
 				},
 			},
 			expectedSpec: OpenAPISpec{
-				OpenAPI: "3.0.0",
+				OpenAPI: "3.1.0",
 				Info: OpenAPIInfo{
 					Title:   "Magic Mock API",
 					Version: "1.0",
@@ -39,6 +45,12 @@ func TestGenerateOpenAPI(t *testing.T) {
 						},
 					},
 					"/status/{statusCode}": {
+						Parameters: []OpenAPIParameter{{
+							Name:     "statusCode",
+							In:       "path",
+							Required: true,
+							Schema:   &OpenAPISchema{Type: "integer"},
+						}},
 						Get: &OpenAPIOperation{
 							Summary:     "MagicRoute for dynamic responses",
 							Description: "Generates a response dynamically based on request content. The status code can be any value between 100 and 599.",
@@ -69,3 +81,159 @@ func TestGenerateOpenAPI(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateOpenAPI_Routes(t *testing.T) {
+	router := NewRouter()
+	router.TokenAuth = true
+	routes := []*Route{
+		{Path: "/users/{id}", Method: "GET", StatusCode: 200, BodyJSON: map[string]interface{}{"id": 1}, ResponseHeaders: map[string]string{"X-Request-Id": "abc"}},
+		{Path: "/users/{id}", Method: "PATCH", StatusCode: 204, AuthRequired: true},
+		{Path: "/users/{id}", Method: "HEAD", StatusCode: 200},
+		{Path: "/users/{id}", Method: "OPTIONS", StatusCode: 204, RateLimitPerMin: 10},
+		{Path: "/notes", Method: "GET", StatusCode: 200, BodyRaw: "plain text", ResponseHeaders: map[string]string{"Content-Type": "text/plain"}},
+		{Path: "/files/**", Method: "GET", StatusCode: 200},
+		{Path: "/search", Method: "GET", StatusCode: 200, BodyJSON: []interface{}{"a"}, Match: &RequestMatch{Query: map[string]ValueMatch{"q": {Equals: "a"}}}},
+		{Path: "/search", Method: "GET", StatusCode: 200, BodyJSON: []interface{}{"b"}, Match: &RequestMatch{Query: map[string]ValueMatch{"q": {Equals: "b"}}}},
+	}
+	for _, route := range routes {
+		router.AddRoute(route)
+	}
+
+	spec := router.GenerateOpenAPI()
+
+	user := spec.Paths["/users/{id}"]
+	if user.Get == nil || user.Patch == nil || user.Head == nil || user.Options == nil {
+		t.Fatalf("Methods on one path were not all kept: %+v", user)
+	}
+	if len(user.Parameters) != 1 || user.Parameters[0].Name != "id" || user.Parameters[0].In != "path" || !user.Parameters[0].Required {
+		t.Errorf("Wrong path parameters: got %+v", user.Parameters)
+	}
+
+	ok := user.Get.Responses["200"]
+	if got := jsonString(ok.Content["application/json"].Example); got != `{"id":1}` {
+		t.Errorf("Wrong response example: got %v want %v", got, `{"id":1}`)
+	}
+	if got := ok.Headers["X-Request-Id"].Example; got != "abc" {
+		t.Errorf("Wrong response header example: got %v want %v", got, "abc")
+	}
+
+	if len(user.Patch.Security) != 1 {
+		t.Errorf("Route requiring auth has no security requirement")
+	}
+	if _, ok := user.Patch.Responses["401"]; !ok {
+		t.Errorf("Route requiring auth has no 401 response")
+	}
+	if spec.Components == nil || spec.Components.SecuritySchemes[openAPISecurityScheme].Type != "apiKey" {
+		t.Errorf("Security scheme is missing: got %+v", spec.Components)
+	}
+	if _, ok := user.Options.Responses["429"]; !ok {
+		t.Errorf("Rate-limited route has no 429 response")
+	}
+
+	if got := spec.Paths["/notes"].Get.Responses["200"].Content["text/plain"].Example; got != "plain text" {
+		t.Errorf("Wrong text example: got %v want %v", got, "plain text")
+	}
+	if _, ok := spec.Paths["/files/{path}"]; !ok {
+		t.Errorf("Catch-all path was not turned into a parameter")
+	}
+
+	search := spec.Paths["/search"].Get
+	if got := len(search.Responses["200"].Content["application/json"].Examples); got != 2 {
+		t.Errorf("Wrong number of examples for route variants: got %v want %v", got, 2)
+	}
+	if len(search.Parameters) != 1 || search.Parameters[0].Name != "q" || search.Parameters[0].In != "query" {
+		t.Errorf("Wrong match parameters: got %+v", search.Parameters)
+	}
+}
+
+func TestOpenAPIHandler_YAML(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/test", Method: "GET", StatusCode: 200, BodyJSON: map[string]interface{}{"ok": true}})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.yaml", http.NoBody))
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/yaml" {
+		t.Errorf("Wrong content type: got %v want %v", got, "application/yaml")
+	}
+	if !strings.HasPrefix(rr.Body.String(), "openapi: 3.1.0\n") {
+		t.Errorf("YAML does not start with the OpenAPI version: %q", rr.Body.String())
+	}
+
+	var fromYAML map[string]interface{}
+	if err := yaml.Unmarshal(rr.Body.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("Invalid YAML: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/openapi", http.NoBody))
+	var fromJSON OpenAPISpec
+	if err := json.Unmarshal(rr.Body.Bytes(), &fromJSON); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if fromJSON.Paths["/test"].Get == nil {
+		t.Errorf("JSON document is missing GET /test")
+	}
+}
+
+func TestGenerateOpenAPI_AuthRequired(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		tokenAuth bool
+		jwtKeys   bool
+		expected  []map[string][]string
+	}{
+		{desc: "no token", expected: nil},
+		{desc: "token", tokenAuth: true, expected: []map[string][]string{{openAPISecurityScheme: {}}}},
+		{desc: "JWT keys", jwtKeys: true, expected: []map[string][]string{{"jwtAuth": {}}}},
+		{desc: "token and JWT keys", tokenAuth: true, jwtKeys: true, expected: []map[string][]string{{"jwtAuth": {}}, {openAPISecurityScheme: {}}}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			router := NewRouter()
+			router.TokenAuth = tC.tokenAuth
+			router.JWTKeys = tC.jwtKeys
+			router.AddRoute(&Route{Path: "/private", Method: "GET", StatusCode: 200, AuthRequired: true})
+
+			spec := router.GenerateOpenAPI()
+
+			operation := spec.Paths["/private"].Get
+			if !reflect.DeepEqual(operation.Security, tC.expected) {
+				t.Errorf("Wrong security requirements: got %v want %v", operation.Security, tC.expected)
+			}
+			if _, ok := operation.Responses["401"]; ok != (tC.expected != nil) {
+				t.Errorf("401 response declared: got %v want %v", ok, tC.expected != nil)
+			}
+			if (spec.Components != nil) != (tC.expected != nil) {
+				t.Errorf("Security schemes declared: got %+v", spec.Components)
+			}
+		})
+	}
+}
+
+func TestGenerateOpenAPI_RouteAuth(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/private", Method: "GET", StatusCode: 200, Auth: &RouteAuth{
+		Bearer:  []string{"t0ken"},
+		APIKeys: []APIKey{{Key: "q", Query: "api_key"}},
+	}})
+	router.AddRoute(&Route{Path: "/claims", Method: "GET", StatusCode: 200, Auth: &RouteAuth{JWT: &JWTAuth{Scopes: []string{"read"}}}})
+
+	spec := router.GenerateOpenAPI()
+
+	want := []map[string][]string{{"bearerAuth": {}}, {"query_api_key": {}}}
+	if got := spec.Paths["/private"].Get.Security; !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong security requirements: got %v want %v", got, want)
+	}
+	if got := spec.Components.SecuritySchemes["bearerAuth"]; got.Type != "http" || got.Scheme != "bearer" {
+		t.Errorf("Wrong bearer scheme: got %+v", got)
+	}
+	if got := spec.Components.SecuritySchemes["query_api_key"]; got.Type != "apiKey" || got.In != "query" || got.Name != "api_key" {
+		t.Errorf("Wrong API key scheme: got %+v", got)
+	}
+	if got := spec.Components.SecuritySchemes["jwtAuth"]; got.Scheme != "bearer" || got.BearerFormat != "JWT" {
+		t.Errorf("Wrong JWT scheme: got %+v", got)
+	}
+}
@@ -1,42 +1,59 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
 // LoadOpenAPIFromFile loads the OpenAPI schema from a file.
-func LoadOpenAPIFromFile(filepath string) (*openapi3.T, error) {
-	data, err := os.ReadFile(filepath)
+func LoadOpenAPIFromFile(path string) (*openapi3.T, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return ParseOpenAPIFromText(string(data))
+	return parseOpenAPI(data, specLocation(path))
 }
 
 // LoadOpenAPIFromURL loads the OpenAPI schema from a given URL.
 func LoadOpenAPIFromURL(url string) (*openapi3.T, error) {
-	resp, err := http.Get(url)
+	data, err := fetchSpec(url)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	return ParseOpenAPIFromText(string(data))
+	return parseOpenAPI(data, specLocation(url))
 }
 
-// ParseOpenAPIFromText parses the OpenAPI schema from raw text input.
+// ParseOpenAPIFromText parses the OpenAPI schema from raw text input. Swagger
+// 2.0 documents are converted to OpenAPI 3.
 func ParseOpenAPIFromText(input string) (*openapi3.T, error) {
+	return parseOpenAPI([]byte(input), nil)
+}
+
+// parseOpenAPI parses a spec read from location, against which relative
+// $refs to other files are resolved. Without a location only refs within
+// the document can be followed.
+func parseOpenAPI(data []byte, location *url.URL) (*openapi3.T, error) {
+	version := specVersionOf(data)
+	switch {
+	case version.Swagger != "":
+		return convertSwagger(data)
+	case version.AsyncAPI != "":
+		return nil, fmt.Errorf("asyncapi %s document is not an OpenAPI spec", version.AsyncAPI)
+	}
 	loader := openapi3.NewLoader()
-	return loader.LoadFromData([]byte(input))
+	if location == nil {
+		return loader.LoadFromData(data)
+	}
+	loader.IsExternalRefsAllowed = true
+	return loader.LoadFromDataWithPath(data, location)
 }
 
 func ParseOpenAPISchema(input string) (*openapi3.T, error) {
@@ -58,36 +75,127 @@ func isFile(s string) bool {
 	_, err := os.Stat(s)
 	return err == nil
 }
+
+// MapOpenAPIRoutes returns a route for every operation. Each answers with its
+// default response and keeps the others for the Prefer header.
 func MapOpenAPIRoutes(swagger *openapi3.T) ([]Route, error) {
 	var routes []Route
 
 	for path, pathItem := range swagger.Paths {
-		for method := range pathItem.Operations() {
+		for method, operation := range pathItem.Operations() {
 			route := Route{
 				Path:       path,
 				Method:     method,
 				StatusCode: 200,
 			}
+			if err := mapOpenAPIOperation(&route, swagger, path, pathItem, operation); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
 			routes = append(routes, route)
 		}
 	}
 
 	return routes, nil
 }
+
+// LoadRoutesFromOpenAPI adds a route for every operation in the spec, given
+// as a URL, a file path or the document itself.
 func (r *Router) LoadRoutesFromOpenAPI(document string) error {
-	swagger, err := ParseOpenAPISchema(document)
+	return r.loadOpenAPI(document, make(map[string]string))
+}
+
+// LoadRoutesFromOpenAPISpecs loads several specs in order. An operation found
+// in more than one of them is taken from the last, with a warning.
+func (r *Router) LoadRoutesFromOpenAPISpecs(documents []string) error {
+	seen := make(map[string]string)
+	for _, document := range documents {
+		if err := r.loadOpenAPI(document, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Router) loadOpenAPI(document string, seen map[string]string) error {
+	source := openAPISource(document)
+	// A single line can only be a file name, so say so rather than report
+	// it as a broken inline spec.
+	if source == "inline spec" && !strings.ContainsAny(document, "\n{") {
+		return fmt.Errorf("openapi %s: no such file", document)
+	}
+	data, err := readSpec(document)
 	if err != nil {
-		return err
+		return fmt.Errorf("openapi %s: %w", source, err)
+	}
+	if version := specVersionOf(data); version.AsyncAPI != "" {
+		routes, err := MapAsyncAPIRoutes(data)
+		if err != nil {
+			return fmt.Errorf("asyncapi %s: %w", source, err)
+		}
+		return r.addLoadedRoutes(routes, source, "", seen)
+	}
+
+	swagger, err := parseOpenAPI(data, specLocation(document))
+	if err != nil {
+		return fmt.Errorf("openapi %s: %w", source, err)
+	}
+	if err := swagger.Validate(context.Background()); err != nil {
+		return fmt.Errorf("openapi %s: invalid spec: %w", source, err)
 	}
 
 	routes, err := MapOpenAPIRoutes(swagger)
 	if err != nil {
-		return err
+		return fmt.Errorf("openapi %s: %w", source, err)
 	}
+	return r.addLoadedRoutes(routes, source, "", seen)
+}
+
+// readSpec returns the document at a URL or file path, or the input itself
+// when it is neither.
+func readSpec(input string) ([]byte, error) {
+	switch {
+	case isURL(input):
+		return fetchSpec(input)
+	case isFile(input):
+		return os.ReadFile(input)
+	}
+	return []byte(input), nil
+}
 
-	for _, route := range routes {
-		r.AddRoute(&route)
+// fetchSpec downloads the document at a URL. Anything but a 2xx answer is an
+// error, so an error page is not parsed as the spec.
+func fetchSpec(location string) ([]byte, error) {
+	resp, err := http.Get(location)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s returned %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
 
+// specLocation returns where a spec given as a URL or file path was read
+// from, or nil for an inline spec.
+func specLocation(input string) *url.URL {
+	switch {
+	case isURL(input):
+		location, err := url.Parse(input)
+		if err != nil {
+			return nil
+		}
+		return location
+	case isFile(input):
+		return &url.URL{Path: filepath.ToSlash(input)}
+	}
 	return nil
 }
+
+// openAPISource names a spec in errors and warnings.
+func openAPISource(document string) string {
+	if isURL(document) || isFile(document) {
+		return document
+	}
+	return "inline spec"
+}
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -50,7 +54,106 @@ func TestLoadRoutesFromOpenAPI(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Validate that the route exists in the router
-	route, exists := router.Routes["/test"]
+	route, exists := router.Routes["GET /test"]
 	assert.True(t, exists)
 	assert.NotNil(t, route)
 }
+
+func TestLoadRoutesFromOpenAPISpecs(t *testing.T) {
+	dir := t.TempDir()
+	specPath := filepath.Join(dir, "api.yaml")
+	assert.NoError(t, os.WriteFile(specPath, []byte(mockOpenAPIDocument), 0o644))
+
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPISpecs([]string{specPath}))
+	assert.Equal(t, http.StatusOK, serveStatus(router, "GET", "/test"))
+
+	// Routes files loaded afterwards override the spec's operations.
+	routesPath := filepath.Join(dir, "routes.json")
+	assert.NoError(t, os.WriteFile(routesPath, []byte(`[{"path": "/test", "method": "GET", "status_code": 418}]`), 0o644))
+	assert.NoError(t, router.LoadRoutesFromFiles([]string{routesPath}))
+	assert.Equal(t, http.StatusTeapot, serveStatus(router, "GET", "/test"))
+}
+
+func TestLoadRoutesFromOpenAPISpecs_Errors(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	assert.NoError(t, os.WriteFile(invalidPath, []byte(`
+openapi: 3.0.0
+info:
+  title: Broken API
+paths:
+  /test:
+    get:
+      responses:
+        '200':
+          description: OK
+`), 0o644))
+
+	testCases := []struct {
+		desc     string
+		document string
+		contains string
+	}{
+		{desc: "invalid spec file", document: invalidPath, contains: "openapi " + invalidPath + ": invalid spec"},
+		{desc: "missing file", document: filepath.Join(dir, "missing.yaml"), contains: "missing.yaml: no such file"},
+		{desc: "invalid inline spec", document: "openapi: 3.0.0\npaths: [", contains: "openapi inline spec"},
+		{desc: "URL not found", document: upstream.URL + "/missing.yaml", contains: "returned 404 Not Found"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := NewRouter().LoadRoutesFromOpenAPISpecs([]string{tC.document})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tC.contains)
+			}
+		})
+	}
+}
+
+// Specs that refer to schemas in a file next to them.
+const (
+	mockSplitSpec = `
+openapi: 3.0.0
+info:
+  title: Split API
+  version: 0.1.0
+paths:
+  /pets/1:
+    get:
+      responses:
+        '200':
+          description: A pet
+          content:
+            application/json:
+              schema:
+                $ref: 'schemas.yaml#/Pet'
+`
+	mockSplitSchemas = `
+Pet:
+  type: object
+  properties:
+    name:
+      type: string
+      example: Rex
+`
+)
+
+func TestLoadRoutesFromOpenAPI_RelativeRefs(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "api.yaml"), []byte(mockSplitSpec), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "schemas.yaml"), []byte(mockSplitSchemas), 0o644))
+	upstream := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer upstream.Close()
+
+	for _, document := range []string{filepath.Join(dir, "api.yaml"), upstream.URL + "/api.yaml"} {
+		router := NewRouter()
+		if assert.NoError(t, router.LoadRoutesFromOpenAPI(document), document) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/pets/1", http.NoBody))
+			assert.JSONEq(t, `{"name": "Rex"}`, rr.Body.String(), document)
+		}
+	}
+}
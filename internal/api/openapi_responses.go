@@ -0,0 +1,330 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// openAPIStatusExtension on an operation picks the status it answers with by
+// default instead of its lowest 2xx.
+const openAPIStatusExtension = "x-faux-status"
+
+// openAPIRoute keeps every response an operation declares, so that a Prefer
+// header can ask for one other than the default.
+type openAPIRoute struct {
+	responses map[int]*openAPIResponse
+	// fallback is the operation's default response, used for any status it
+	// does not declare.
+	fallback *openAPIResponse
+	// defaultStatus is the status served without a Prefer header.
+	defaultStatus int
+
+	// The operation and where it sits in the spec, to validate requests.
+	spec      *openapi3.T
+	path      string
+	pathItem  *openapi3.PathItem
+	operation *openapi3.Operation
+}
+
+// openAPIResponse is one declared response with its default body and its
+// named examples.
+type openAPIResponse struct {
+	RouteResponse
+	examples map[string]RouteResponse
+}
+
+// mapOpenAPIOperation collects the operation's responses and sets the route's
+// status, headers and body to its default one.
+func mapOpenAPIOperation(route *Route, spec *openapi3.T, path string, pathItem *openapi3.PathItem, operation *openapi3.Operation) error {
+	mocked := &openAPIRoute{
+		responses: make(map[int]*openAPIResponse),
+		spec:      spec,
+		path:      path,
+		pathItem:  pathItem,
+		operation: operation,
+	}
+
+	for code, ref := range operation.Responses {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		response := newOpenAPIResponse(ref.Value)
+		if code == "default" {
+			mocked.fallback = response
+			continue
+		}
+		status, err := openAPIStatus(code)
+		if err != nil {
+			return err
+		}
+		response.StatusCode = status
+		mocked.responses[status] = response
+	}
+
+	status, err := defaultOpenAPIStatus(operation, mocked)
+	if err != nil {
+		return err
+	}
+	mocked.defaultStatus = status
+
+	if response := mocked.response(status); response != nil {
+		route.StatusCode = response.StatusCode
+		route.ResponseHeaders = response.ResponseHeaders
+		route.BodyRaw = response.BodyRaw
+		route.BodyJSON = response.BodyJSON
+	} else {
+		route.StatusCode = status
+	}
+	route.openapi = mocked
+	return nil
+}
+
+// openAPIStatus parses a response key such as "404" or the range "2XX",
+// which stands for its first status.
+func openAPIStatus(code string) (int, error) {
+	if len(code) == 3 && strings.HasSuffix(strings.ToUpper(code), "XX") {
+		code = code[:1] + "00"
+	}
+	status, err := strconv.Atoi(code)
+	if err != nil || status < 100 || status > 599 {
+		return 0, fmt.Errorf("invalid response status %q", code)
+	}
+	return status, nil
+}
+
+// defaultOpenAPIStatus returns the status set with x-faux-status, otherwise
+// the lowest 2xx, otherwise 200 when there is a default response and the
+// lowest declared status if not.
+func defaultOpenAPIStatus(operation *openapi3.Operation, mocked *openAPIRoute) (int, error) {
+	if value, ok := operation.Extensions[openAPIStatusExtension]; ok {
+		var status int
+		switch v := value.(type) {
+		case float64:
+			status = int(v)
+		case int:
+			status = v
+		case string:
+			status, _ = strconv.Atoi(v)
+		case json.RawMessage:
+			_ = json.Unmarshal(v, &status)
+		}
+		if status < 100 || status > 599 {
+			return 0, fmt.Errorf("invalid %s %v", openAPIStatusExtension, value)
+		}
+		return status, nil
+	}
+
+	statuses := make([]int, 0, len(mocked.responses))
+	for status := range mocked.responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		if status >= 200 && status < 300 {
+			return status, nil
+		}
+	}
+	if mocked.fallback != nil || len(statuses) == 0 {
+		return http.StatusOK, nil
+	}
+	return statuses[0], nil
+}
+
+// response returns the declared response for status, falling back to the
+// default response, or nil if there is neither.
+func (m *openAPIRoute) response(status int) *RouteResponse {
+	response, ok := m.responses[status]
+	if !ok {
+		if m.fallback == nil {
+			return nil
+		}
+		response = m.fallback
+	}
+	resp := response.RouteResponse
+	resp.StatusCode = status
+	return &resp
+}
+
+// preferred returns the response asked for with a Prefer header such as
+// "code=404, example=notFound", the way Prism does. An example name alone
+// picks from the default status first. A code that is not a valid HTTP
+// status is ignored.
+func (m *openAPIRoute) preferred(prefer string) (*RouteResponse, bool) {
+	preferences := parsePrefer(prefer)
+	code, example := preferences["code"], preferences["example"]
+	if code == "" && example == "" {
+		return nil, false
+	}
+
+	status := m.defaultStatus
+	if code != "" {
+		var err error
+		// Anything outside 100-599 would make WriteHeader panic.
+		if status, err = strconv.Atoi(code); err != nil || status < 100 || status > 599 {
+			return nil, false
+		}
+	} else if example != "" {
+		status = m.statusWithExample(example)
+	}
+
+	resp := m.response(status)
+	if resp == nil {
+		resp = &RouteResponse{StatusCode: status}
+	}
+	if example != "" {
+		declared, ok := m.responses[status]
+		if !ok {
+			declared = m.fallback
+		}
+		if declared != nil {
+			if named, ok := declared.examples[example]; ok {
+				resp.BodyRaw, resp.BodyJSON = named.BodyRaw, named.BodyJSON
+			}
+		}
+	}
+	return resp, true
+}
+
+// statusWithExample finds the status that has the named example, trying the
+// default status before the others in order.
+func (m *openAPIRoute) statusWithExample(example string) int {
+	if response, ok := m.responses[m.defaultStatus]; ok {
+		if _, ok := response.examples[example]; ok {
+			return m.defaultStatus
+		}
+	}
+	statuses := make([]int, 0, len(m.responses))
+	for status := range m.responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		if _, ok := m.responses[status].examples[example]; ok {
+			return status
+		}
+	}
+	return m.defaultStatus
+}
+
+// parsePrefer splits a Prefer header into its preferences. Both commas and
+// semicolons separate them, and values may be quoted.
+func parsePrefer(value string) map[string]string {
+	preferences := make(map[string]string)
+	for _, part := range strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ';' }) {
+		name, v, _ := strings.Cut(part, "=")
+		preferences[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return preferences
+}
+
+// newOpenAPIResponse picks the response's media type, preferring JSON, and
+// builds its body from the example, the first named example or the schema,
+// in that order. Declared headers get their example or a generated value.
+func newOpenAPIResponse(response *openapi3.Response) *openAPIResponse {
+	result := &openAPIResponse{examples: make(map[string]RouteResponse)}
+
+	headers := make(map[string]string)
+	for name, ref := range response.Headers {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		if value := parameterExample(&ref.Value.Parameter); value != nil {
+			headers[name] = exampleText(value)
+		}
+	}
+
+	contentType, mediaType := pickMediaType(response.Content)
+	if mediaType != nil {
+		headers["Content-Type"] = contentType
+
+		names := make([]string, 0, len(mediaType.Examples))
+		for name, ref := range mediaType.Examples {
+			if ref != nil && ref.Value != nil && ref.Value.Value != nil {
+				names = append(names, name)
+				result.examples[name] = exampleResponse(contentType, ref.Value.Value)
+			}
+		}
+		sort.Strings(names)
+
+		var body interface{}
+		switch {
+		case mediaType.Example != nil:
+			body = mediaType.Example
+		case len(names) > 0:
+			body = mediaType.Examples[names[0]].Value.Value
+		default:
+			body = exampleFromSchema(mediaType.Schema)
+		}
+		if body != nil {
+			example := exampleResponse(contentType, body)
+			result.BodyRaw, result.BodyJSON = example.BodyRaw, example.BodyJSON
+		}
+	}
+
+	if len(headers) > 0 {
+		result.ResponseHeaders = headers
+	}
+	return result
+}
+
+// pickMediaType returns application/json if the content has it, then any
+// other JSON type, then the first type by name.
+func pickMediaType(content openapi3.Content) (string, *openapi3.MediaType) {
+	if len(content) == 0 {
+		return "", nil
+	}
+	if mediaType, ok := content["application/json"]; ok {
+		return "application/json", mediaType
+	}
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	for _, contentType := range types {
+		if strings.Contains(contentType, "json") {
+			return contentType, content[contentType]
+		}
+	}
+	return types[0], content[types[0]]
+}
+
+// exampleResponse holds an example as a JSON body, or as raw text when the
+// media type is not JSON and the example is a string.
+func exampleResponse(contentType string, value interface{}) RouteResponse {
+	if text, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		return RouteResponse{BodyRaw: text}
+	}
+	return RouteResponse{BodyJSON: value}
+}
+
+func parameterExample(parameter *openapi3.Parameter) interface{} {
+	if parameter.Example != nil {
+		return parameter.Example
+	}
+	names := make([]string, 0, len(parameter.Examples))
+	for name, ref := range parameter.Examples {
+		if ref != nil && ref.Value != nil && ref.Value.Value != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return parameter.Examples[names[0]].Value.Value
+	}
+	return exampleFromSchema(parameter.Schema)
+}
+
+// exampleText formats an example for a header value.
+func exampleText(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	return jsonString(value)
+}
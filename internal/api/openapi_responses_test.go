@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockOpenAPIResponses = `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        '404':
+          description: Not found
+          content:
+            application/json:
+              examples:
+                notFound: {value: {error: no such pet}}
+                gone: {value: {error: pet was removed}}
+        '200':
+          description: A pet
+          headers:
+            X-Rate-Limit:
+              schema: {type: integer, minimum: 100}
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id: {type: integer, format: int64}
+                  name: {type: string}
+                  status: {type: string, enum: [available, sold]}
+        '201':
+          description: Never chosen over 200
+  /pets:
+    post:
+      x-faux-status: 202
+      responses:
+        '201':
+          description: Created
+          content:
+            text/plain:
+              example: created
+        '202':
+          description: Accepted
+        default:
+          description: Error
+          content:
+            application/json:
+              example: {error: unexpected}
+`
+
+func TestMapOpenAPIRoutes_Responses(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockOpenAPIResponses))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/pets/1", http.NoBody))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "100", rr.Header().Get("X-Rate-Limit"))
+	assert.JSONEq(t, `{"id": 0, "name": "string", "status": "available"}`, rr.Body.String())
+
+	// x-faux-status overrides the lowest 2xx.
+	assert.Equal(t, http.StatusAccepted, serveStatus(router, "POST", "/pets"))
+}
+
+func TestMapOpenAPIRoutes_Prefer(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockOpenAPIResponses))
+
+	testCases := []struct {
+		desc   string
+		method string
+		path   string
+		prefer string
+		status int
+		body   string
+	}{
+		{desc: "code", method: "GET", path: "/pets/1", prefer: "code=404", status: http.StatusNotFound, body: `{"error":"pet was removed"}`},
+		{desc: "code and example", method: "GET", path: "/pets/1", prefer: "code=404, example=notFound", status: http.StatusNotFound, body: `{"error":"no such pet"}`},
+		{desc: "example only", method: "GET", path: "/pets/1", prefer: `example="notFound"`, status: http.StatusNotFound, body: `{"error":"no such pet"}`},
+		{desc: "non-JSON example", method: "POST", path: "/pets", prefer: "code=201", status: http.StatusCreated, body: "created"},
+		{desc: "undeclared code uses default response", method: "POST", path: "/pets", prefer: "code=500", status: http.StatusInternalServerError, body: `{"error":"unexpected"}`},
+		{desc: "undeclared code without default", method: "GET", path: "/pets/1", prefer: "code=503", status: http.StatusServiceUnavailable, body: ""},
+		{desc: "code below 100 is ignored", method: "POST", path: "/pets", prefer: "code=42", status: http.StatusAccepted, body: ""},
+		{desc: "code above 599 is ignored", method: "POST", path: "/pets", prefer: "code=600", status: http.StatusAccepted, body: ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(tC.method, tC.path, http.NoBody)
+			req.Header.Set("Prefer", tC.prefer)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tC.status, rr.Code)
+			assert.Equal(t, tC.body, rr.Body.String())
+			if tC.status != http.StatusOK {
+				assert.Empty(t, rr.Header().Get("X-Rate-Limit"))
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// Request validation modes for routes mapped from an OpenAPI spec.
+const (
+	// ValidationOff serves requests without checking them.
+	ValidationOff = "off"
+	// ValidationEnforce rejects requests that break the spec with a 400.
+	ValidationEnforce = "enforce"
+	// ValidationLog logs violations but serves the request anyway.
+	ValidationLog = "log"
+)
+
+// Violation is one way a request breaks the operation it was matched to.
+type Violation struct {
+	// In is where the problem is: path, query, header, cookie or body.
+	In   string `json:"in"`
+	Name string `json:"name,omitempty"`
+	// Pointer is the JSON pointer to the offending value inside the body or
+	// parameter, when there is one.
+	Pointer string `json:"pointer,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+type validationError struct {
+	Error      string      `json:"error"`
+	Violations []Violation `json:"violations"`
+}
+
+// validateOpenAPIRequest checks a request against the operation its route was
+// mapped from. It reports false when the request was rejected and a response
+// has already been written.
+func (r *Router) validateOpenAPIRequest(w http.ResponseWriter, req *http.Request, route *Route) bool {
+	mode := r.OpenAPIValidation
+	if route.openapi == nil || route.openapi.operation == nil || mode == "" || mode == ValidationOff {
+		return true
+	}
+
+	violations, err := route.openapi.validate(req)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return false
+	}
+	if len(violations) == 0 {
+		return true
+	}
+
+	reasons := make([]string, len(violations))
+	for i, v := range violations {
+		reasons[i] = v.String()
+	}
+	log.Printf("Request %s %s does not match the OpenAPI spec: %s", req.Method, req.URL.Path, strings.Join(reasons, "; "))
+
+	if mode != ValidationEnforce {
+		return true
+	}
+	writeJSON(w, http.StatusBadRequest, validationError{
+		Error:      "request does not match the OpenAPI spec",
+		Violations: violations,
+	})
+	return false
+}
+
+// validate returns every violation of the operation by req. The body is put
+// back afterwards, and defaults from the spec are not filled in, so the
+// request reaches the route as it was sent.
+func (m *openAPIRoute) validate(req *http.Request) ([]Violation, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}()
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: PathParams(req),
+		Route: &routers.Route{
+			Spec:      m.spec,
+			Path:      m.path,
+			PathItem:  m.pathItem,
+			Method:    req.Method,
+			Operation: m.operation,
+		},
+		Options: &openapi3filter.Options{
+			MultiError:          true,
+			SkipSettingDefaults: true,
+			// Authentication is the auth middleware's job.
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+
+	err = openapi3filter.ValidateRequest(context.Background(), input)
+	if err == nil {
+		return nil, nil
+	}
+	return violationsOf(err), nil
+}
+
+// violationsOf flattens the errors from openapi3filter into violations.
+func violationsOf(err error) []Violation {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var violations []Violation
+		for _, e := range multi {
+			violations = append(violations, violationsOf(e)...)
+		}
+		return violations
+	}
+
+	var requestErr *openapi3filter.RequestError
+	if !errors.As(err, &requestErr) {
+		return []Violation{{Reason: err.Error()}}
+	}
+
+	violation := Violation{Reason: requestErr.Reason}
+	switch {
+	case requestErr.Parameter != nil:
+		violation.In = requestErr.Parameter.In
+		violation.Name = requestErr.Parameter.Name
+	case requestErr.RequestBody != nil:
+		violation.In = "body"
+	}
+
+	schemaErrs := schemaErrorsOf(requestErr.Err)
+	if len(schemaErrs) == 0 {
+		if requestErr.Err != nil {
+			switch reason := requestErr.Err.Error(); {
+			case violation.Reason == "":
+				violation.Reason = reason
+			case reason != violation.Reason:
+				violation.Reason += ": " + reason
+			}
+		}
+		return []Violation{violation}
+	}
+
+	violations := make([]Violation, 0, len(schemaErrs))
+	for _, schemaErr := range schemaErrs {
+		v := violation
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			v.Pointer = "/" + strings.Join(pointer, "/")
+		}
+		v.Reason = schemaErr.Reason
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+func schemaErrorsOf(err error) []*openapi3.SchemaError {
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var schemaErrs []*openapi3.SchemaError
+		for _, e := range multi {
+			schemaErrs = append(schemaErrs, schemaErrorsOf(e)...)
+		}
+		return schemaErrs
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return []*openapi3.SchemaError{schemaErr}
+	}
+	return nil
+}
+
+func (v Violation) String() string {
+	location := v.In
+	if v.Name != "" {
+		location += " " + v.Name
+	}
+	if v.Pointer != "" {
+		location += " " + v.Pointer
+	}
+	if location == "" {
+		return v.Reason
+	}
+	return location + ": " + v.Reason
+}
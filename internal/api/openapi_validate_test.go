@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockOpenAPIValidation = `
+openapi: 3.0.0
+info: {title: Pets, version: "1"}
+paths:
+  /pets/{id}:
+    put:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+        - {name: dryRun, in: query, required: true, schema: {type: boolean}}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name: {type: string}
+                age: {type: integer, minimum: 0}
+      responses:
+        '200':
+          description: Updated
+          content:
+            application/json:
+              example: {updated: true}
+`
+
+func TestValidateOpenAPIRequest(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		mode       string
+		path       string
+		body       string
+		wantStatus int
+		wantIn     []string
+	}{
+		{
+			desc:       "valid request",
+			mode:       ValidationEnforce,
+			path:       "/pets/1?dryRun=true",
+			body:       `{"name": "Rex", "age": 3}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			desc:       "every violation is reported",
+			mode:       ValidationEnforce,
+			path:       "/pets/rex",
+			body:       `{"age": -1}`,
+			wantStatus: http.StatusBadRequest,
+			wantIn:     []string{"path", "query", "body", "body"},
+		},
+		{
+			desc:       "log mode serves the request",
+			mode:       ValidationLog,
+			path:       "/pets/rex",
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			desc:       "off",
+			mode:       ValidationOff,
+			path:       "/pets/rex",
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			router := NewRouter()
+			router.OpenAPIValidation = tC.mode
+			assert.NoError(t, router.LoadRoutesFromOpenAPI(mockOpenAPIValidation))
+
+			req := httptest.NewRequest("PUT", tC.path, strings.NewReader(tC.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, tC.wantStatus, rr.Code)
+			if tC.wantIn == nil {
+				return
+			}
+
+			var got validationError
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			var in []string
+			for _, v := range got.Violations {
+				in = append(in, v.In)
+				assert.NotEmpty(t, v.Reason)
+			}
+			assert.ElementsMatch(t, tC.wantIn, in)
+		})
+	}
+}
+
+func TestValidateOpenAPIRequest_Pointer(t *testing.T) {
+	router := NewRouter()
+	router.OpenAPIValidation = ValidationEnforce
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockOpenAPIValidation))
+
+	req := httptest.NewRequest("PUT", "/pets/1?dryRun=true", strings.NewReader(`{"name": "Rex", "age": -1}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var got validationError
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	if assert.Len(t, got.Violations, 1) {
+		assert.Equal(t, "body", got.Violations[0].In)
+		assert.Equal(t, "/age", got.Violations[0].Pointer)
+	}
+}
+
+func TestValidateOpenAPIRequest_KeepsBody(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockOpenAPIValidation))
+
+	req := httptest.NewRequest("PUT", "/pets/1?dryRun=true", strings.NewReader(`{"name": "Rex"}`))
+	req.Header.Set("Content-Type", "application/json")
+	route, params, _ := router.findRoute(req)
+	if !assert.NotNil(t, route) {
+		return
+	}
+	req = withPathParams(req, params)
+
+	violations, err := route.openapi.validate(req)
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "Rex"}`, string(body))
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Segment kinds, in order of increasing precedence.
+const (
+	segCatchAll = iota
+	segWildcard
+	segParam
+	segLiteral
+)
+
+type contextKey int
+
+const (
+	pathParamsKey contextKey = iota
+	journalEntryKey
+	jwtClaimsKey
+	routeChoiceKey
+)
+
+// matchPath matches a request path against a route pattern. A pattern segment
+// may be a literal, {name} for a named parameter, * for any single segment or
+// ** for the remainder of the path. Named values are captured under their
+// name, wildcards under "*" and "**".
+func matchPath(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	params := make(map[string]string)
+
+	for i, part := range patternParts {
+		kind := segmentKind(part)
+		if kind == segCatchAll {
+			params["**"] = strings.Join(pathParts[i:], "/")
+			return params, true
+		}
+		if i >= len(pathParts) {
+			return nil, false
+		}
+
+		switch kind {
+		case segLiteral:
+			if part != pathParts[i] {
+				return nil, false
+			}
+		case segParam:
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[part[1:len(part)-1]] = pathParts[i]
+		case segWildcard:
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params["*"] = pathParts[i]
+		}
+	}
+
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+	return params, true
+}
+
+func segmentKind(part string) int {
+	switch {
+	case part == "**":
+		return segCatchAll
+	case part == "*":
+		return segWildcard
+	case len(part) > 2 && strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+		return segParam
+	default:
+		return segLiteral
+	}
+}
+
+// isPathPattern reports whether the path contains any parameter or wildcard
+// segments.
+func isPathPattern(path string) bool {
+	for _, part := range strings.Split(path, "/") {
+		if segmentKind(part) != segLiteral {
+			return true
+		}
+	}
+	return false
+}
+
+// comparePatterns orders two patterns by precedence. It returns a positive
+// number if a should win over b, negative if b wins and zero if they rank the
+// same. Segments are compared left to right, so a literal beats a parameter
+// which beats a wildcard at the first position where they differ.
+func comparePatterns(a, b string) int {
+	aParts := strings.Split(a, "/")
+	bParts := strings.Split(b, "/")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if diff := segmentKind(aParts[i]) - segmentKind(bParts[i]); diff != 0 {
+			return diff
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// PathParams returns the values captured from the route pattern for the
+// request, or nil if the route had none.
+func PathParams(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(pathParamsKey).(map[string]string)
+	return params
+}
+
+func withPathParams(req *http.Request, params map[string]string) *http.Request {
+	if len(params) == 0 {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), pathParamsKey, params))
+}
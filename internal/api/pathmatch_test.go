@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc           string
+		pattern        string
+		path           string
+		expectedMatch  bool
+		expectedParams map[string]string
+	}{
+		{
+			desc:           "Literal",
+			pattern:        "/users",
+			path:           "/users",
+			expectedMatch:  true,
+			expectedParams: map[string]string{},
+		},
+		{
+			desc:           "Named parameter",
+			pattern:        "/users/{id}/orders/{orderId}",
+			path:           "/users/42/orders/7",
+			expectedMatch:  true,
+			expectedParams: map[string]string{"id": "42", "orderId": "7"},
+		},
+		{
+			desc:          "Parameter does not match empty segment",
+			pattern:       "/users/{id}",
+			path:          "/users/",
+			expectedMatch: false,
+		},
+		{
+			desc:           "Single segment wildcard",
+			pattern:        "/files/*",
+			path:           "/files/report.pdf",
+			expectedMatch:  true,
+			expectedParams: map[string]string{"*": "report.pdf"},
+		},
+		{
+			desc:          "Single segment wildcard does not cross slashes",
+			pattern:       "/files/*",
+			path:          "/files/a/b",
+			expectedMatch: false,
+		},
+		{
+			desc:           "Catch-all",
+			pattern:        "/static/**",
+			path:           "/static/css/site.css",
+			expectedMatch:  true,
+			expectedParams: map[string]string{"**": "css/site.css"},
+		},
+		{
+			desc:          "Too short",
+			pattern:       "/users/{id}/orders",
+			path:          "/users/42",
+			expectedMatch: false,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			params, ok := matchPath(tC.pattern, tC.path)
+			if ok != tC.expectedMatch {
+				t.Fatalf("Expected match %v, but got %v", tC.expectedMatch, ok)
+			}
+			if ok && !reflect.DeepEqual(params, tC.expectedParams) {
+				t.Errorf("Expected params %v, but got %v", tC.expectedParams, params)
+			}
+		})
+	}
+}
+
+func TestServeHTTP_PathPrecedence(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/users/**", Method: "GET", StatusCode: 202})
+	router.AddRoute(&Route{Path: "/users/*", Method: "GET", StatusCode: 203})
+	router.AddRoute(&Route{Path: "/users/{id}", Method: "GET", StatusCode: 201})
+	router.AddRoute(&Route{Path: "/users/me", Method: "GET", StatusCode: 200})
+
+	testCases := []struct {
+		path     string
+		expected int
+	}{
+		{"/users/me", 200},
+		{"/users/42", 201},
+		{"/users/42/orders", 202},
+	}
+
+	for _, tC := range testCases {
+		req := httptest.NewRequest("GET", tC.path, http.NoBody)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != tC.expected {
+			t.Errorf("%s: got status %v want %v", tC.path, rr.Code, tC.expected)
+		}
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/users/{id}", Method: "GET", StatusCode: 200})
+
+	req := httptest.NewRequest("GET", "/users/42", http.NoBody)
+	route, params, _ := router.findRoute(req)
+	if route == nil || route.Path != "/users/{id}" {
+		t.Fatalf("Route not matched: %v", route)
+	}
+
+	req = withPathParams(req, params)
+	if got := PathParams(req)["id"]; got != "42" {
+		t.Errorf("Expected id 42, but got %q", got)
+	}
+}
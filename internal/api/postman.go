@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// postmanResponseHeader picks one of several saved examples for the same
+// request, the way Postman's own mock servers do.
+const postmanResponseHeader = "X-Mock-Response-Name"
+
+// PostmanCollection is a Postman v2.1 collection. Only the fields needed to
+// build routes are declared.
+type PostmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item []PostmanItem `json:"item"`
+}
+
+// PostmanItem is either a folder holding more items or a request with its
+// saved example responses.
+type PostmanItem struct {
+	Name     string            `json:"name"`
+	Item     []PostmanItem     `json:"item,omitempty"`
+	Request  *PostmanRequest   `json:"request,omitempty"`
+	Response []PostmanResponse `json:"response,omitempty"`
+}
+
+type PostmanRequest struct {
+	Method string     `json:"method"`
+	URL    PostmanURL `json:"url"`
+}
+
+// PostmanURL is written either as a plain string or as an object; both are
+// decoded into the object form.
+type PostmanURL struct {
+	Raw   string          `json:"raw"`
+	Path  []string        `json:"path"`
+	Query []PostmanHeader `json:"query"`
+}
+
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = PostmanURL{Raw: raw}
+		return nil
+	}
+	type plain PostmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// PostmanHeader is a key and value pair, used for headers and query
+// parameters.
+type PostmanHeader struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+type PostmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *PostmanRequest `json:"originalRequest,omitempty"`
+	Code            int             `json:"code"`
+	Header          []PostmanHeader `json:"header"`
+	Body            string          `json:"body"`
+}
+
+// ParsePostmanCollection reads a collection from a URL, a file or the text
+// itself.
+func ParsePostmanCollection(input string) (*PostmanCollection, error) {
+	data := []byte(input)
+	if isURL(input) {
+		resp, err := http.Get(input)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	} else if isFile(input) {
+		var err error
+		if data, err = os.ReadFile(input); err != nil {
+			return nil, err
+		}
+	}
+
+	return parsePostman(data)
+}
+
+func parsePostman(data []byte) (*PostmanCollection, error) {
+	var collection PostmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, err
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, "v2.1") {
+		return nil, fmt.Errorf("unsupported Postman collection schema %s", collection.Info.Schema)
+	}
+	return &collection, nil
+}
+
+// MapPostmanRoutes turns every request in the collection into routes, one per
+// saved example. The first example for a method, path and query answers by
+// default; the others are picked with the X-Mock-Response-Name header. A
+// request without examples gets an empty 200.
+func MapPostmanRoutes(collection *PostmanCollection) ([]Route, error) {
+	var routes []Route
+	seen := make(map[string]bool)
+
+	var walk func(items []PostmanItem) error
+	walk = func(items []PostmanItem) error {
+		for _, item := range items {
+			if err := walk(item.Item); err != nil {
+				return err
+			}
+			if item.Request == nil {
+				continue
+			}
+
+			if len(item.Response) == 0 {
+				route, _, err := postmanRoute(item.Request)
+				if err != nil {
+					return fmt.Errorf("postman request %q: %w", item.Name, err)
+				}
+				route.StatusCode = http.StatusOK
+				routes = append(routes, route)
+				continue
+			}
+
+			for _, example := range item.Response {
+				request := item.Request
+				if example.OriginalRequest != nil {
+					request = example.OriginalRequest
+				}
+				route, key, err := postmanRoute(request)
+				if err != nil {
+					return fmt.Errorf("postman example %q: %w", example.Name, err)
+				}
+
+				route.StatusCode = example.Code
+				if route.StatusCode == 0 {
+					route.StatusCode = http.StatusOK
+				}
+				header := make(http.Header)
+				for _, h := range example.Header {
+					if !h.Disabled {
+						header.Add(h.Key, h.Value)
+					}
+				}
+				route.ResponseHeaders = stableHeaders(header)
+				route.BodyRaw = example.Body
+
+				if seen[key] {
+					if route.Match == nil {
+						route.Match = &RequestMatch{}
+					}
+					route.Match.Headers = map[string]ValueMatch{postmanResponseHeader: {Equals: example.Name}}
+				}
+				seen[key] = true
+				routes = append(routes, route)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(collection.Item); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// postmanRoute builds the request side of a route and the key that tells
+// examples for the same request apart.
+func postmanRoute(request *PostmanRequest) (Route, string, error) {
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	path, query, err := postmanPath(request.URL)
+	if err != nil {
+		return Route{}, "", err
+	}
+	route := Route{
+		Path:   path,
+		Method: strings.ToUpper(method),
+		Match:  queryMatch(query),
+	}
+	return route, recordKey(route.Method, route.Path, query), nil
+}
+
+// postmanPath returns the route path and query for a Postman URL. Path
+// variables written :id or {{id}} become {id} parameters, and query values
+// that are variables only require the parameter to be present.
+func postmanPath(u PostmanURL) (string, url.Values, error) {
+	segments := append([]string(nil), u.Path...)
+	query := url.Values{}
+	for _, q := range u.Query {
+		if !q.Disabled {
+			query.Add(q.Key, q.Value)
+		}
+	}
+
+	if len(segments) == 0 && u.Raw != "" {
+		raw := u.Raw
+		// The host is usually a variable such as {{baseUrl}}.
+		if strings.HasPrefix(raw, "{{") {
+			if end := strings.Index(raw, "}}"); end != -1 {
+				raw = raw[end+2:]
+			}
+		}
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		segments = strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		if len(u.Query) == 0 {
+			query = parsed.Query()
+		}
+	}
+
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":") && len(segment) > 1:
+			segments[i] = "{" + segment[1:] + "}"
+		case strings.HasPrefix(segment, "{{") && strings.HasSuffix(segment, "}}") && len(segment) > 4:
+			segments[i] = "{" + segment[2:len(segment)-2] + "}"
+		}
+	}
+	for _, values := range query {
+		for i, value := range values {
+			if strings.HasPrefix(value, "{{") && strings.HasSuffix(value, "}}") {
+				values[i] = ""
+			}
+		}
+	}
+
+	return "/" + strings.Join(segments, "/"), query, nil
+}
+
+// LoadRoutesFromPostman adds the routes of a Postman v2.1 collection given as
+// a URL, a file path or the collection itself.
+func (r *Router) LoadRoutesFromPostman(document string) error {
+	collection, err := ParsePostmanCollection(document)
+	if err != nil {
+		return err
+	}
+	return r.addPostmanRoutes(collection, "Postman collection", make(map[string]string))
+}
+
+func (r *Router) addPostmanRoutes(collection *PostmanCollection, source string, seen map[string]string) error {
+	routes, err := MapPostmanRoutes(collection)
+	if err != nil {
+		return err
+	}
+	return r.addLoadedRoutes(routes, source, "", seen)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockPostmanCollection = `{
+  "info": {
+    "name": "Users",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "item": [
+    {
+      "name": "Users",
+      "item": [
+        {
+          "name": "Get user",
+          "request": {
+            "method": "GET",
+            "url": {"raw": "{{baseUrl}}/users/:id", "host": ["{{baseUrl}}"], "path": ["users", ":id"]}
+          },
+          "response": [
+            {
+              "name": "Found",
+              "code": 200,
+              "header": [{"key": "Content-Type", "value": "application/json"}],
+              "body": "{\"id\": 1}"
+            },
+            {
+              "name": "Missing",
+              "code": 404,
+              "header": [],
+              "body": "not found"
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "Search",
+      "request": {"method": "GET", "url": "{{baseUrl}}/search?q={{term}}"}
+    }
+  ]
+}`
+
+func TestMapPostmanRoutes(t *testing.T) {
+	collection, err := ParsePostmanCollection(mockPostmanCollection)
+	assert.NoError(t, err)
+
+	routes, err := MapPostmanRoutes(collection)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(routes))
+
+	assert.Equal(t, "/users/{id}", routes[0].Path)
+	assert.Equal(t, http.StatusOK, routes[0].StatusCode)
+	assert.Equal(t, `{"id": 1}`, routes[0].BodyRaw)
+	assert.Nil(t, routes[0].Match)
+
+	assert.Equal(t, http.StatusNotFound, routes[1].StatusCode)
+	assert.Equal(t, "Missing", routes[1].Match.Headers["X-Mock-Response-Name"].Equals)
+
+	assert.Equal(t, "/search", routes[2].Path)
+	assert.True(t, routes[2].Match.Query["q"].Present)
+}
+
+func TestLoadRoutesFromPostman(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromPostman(mockPostmanCollection))
+
+	assert.Equal(t, http.StatusOK, serveStatus(router, "GET", "/users/7"))
+
+	req := httptest.NewRequest("GET", "/users/7", http.NoBody)
+	req.Header.Set("X-Mock-Response-Name", "Missing")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "not found", rr.Body.String())
+
+	_, err := ParsePostmanCollection(`{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`)
+	assert.Error(t, err)
+}
+
+func TestLoadRoutesFromDir_Postman(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "users.postman_collection.json"), []byte(mockPostmanCollection), 0o644))
+
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromDir(dir))
+	assert.Equal(t, http.StatusOK, serveStatus(router, "GET", "/search?q=go"))
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+// ProxyConfig controls how requests are forwarded to an upstream server.
+type ProxyConfig struct {
+	// Target is the upstream base URL that unmatched requests are forwarded
+	// to. When empty, only routes with proxy_to or proxy set are forwarded.
+	Target string
+	// RewriteHost sends the upstream's host in the Host header instead of
+	// the one the client used.
+	RewriteHost bool
+	// Headers are added to every forwarded request.
+	Headers map[string]string
+	// Timeout bounds the whole upstream exchange. Zero means no limit.
+	Timeout time.Duration
+}
+
+// Validate checks that the target, if set, is an absolute http or https URL.
+func (c *ProxyConfig) Validate() error {
+	if c.Target == "" {
+		return nil
+	}
+	return validateProxyURL(c.Target)
+}
+
+// forwards reports whether unmatched requests should be forwarded.
+func (r *Router) forwards() bool {
+	return r.Proxy != nil && r.Proxy.Target != ""
+}
+
+// proxyTarget returns the upstream a matched route forwards to, or "" if the
+// route is served locally.
+func (r *Router) proxyTarget(route *Route) string {
+	if route.ProxyTo != "" {
+		return route.ProxyTo
+	}
+	if route.Proxy && r.Proxy != nil {
+		return r.Proxy.Target
+	}
+	return ""
+}
+
+// proxyHandler forwards requests to target with their headers and body
+// intact.
+func (r *Router) proxyHandler(target string) http.Handler {
+	return r.reverseProxy(target, nil)
+}
+
+// reverseProxy is proxyHandler with a hook that sees every upstream response
+// before it is sent on. A hook error is reported as a bad gateway.
+func (r *Router) reverseProxy(target string, modifyResponse func(*http.Response) error) http.Handler {
+	upstream, err := url.Parse(target)
+	if err != nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, "Invalid upstream URL", http.StatusBadGateway)
+		})
+	}
+
+	config := r.Proxy
+	if config == nil {
+		config = &ProxyConfig{}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		if config.RewriteHost {
+			req.Host = upstream.Host
+		}
+		for name, value := range config.Headers {
+			req.Header.Set(name, value)
+		}
+	}
+	proxy.ModifyResponse = modifyResponse
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		log.Printf("Proxy error for %s %s: %v", req.Method, req.URL, err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			http.Error(w, "Upstream timed out", http.StatusGatewayTimeout)
+			return
+		}
+		http.Error(w, "Upstream unavailable", http.StatusBadGateway)
+	}
+
+	if config.Timeout <= 0 {
+		return proxy
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), config.Timeout)
+		defer cancel()
+		proxy.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// validateProxyURL checks that target is an absolute http or https URL.
+func validateProxyURL(target string) error {
+	upstream, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+		return fmt.Errorf("%q is not an absolute http or https URL", target)
+	}
+	return nil
+}
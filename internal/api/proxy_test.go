@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEchoUpstream starts an upstream that echoes back what it received.
+func newEchoUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		w.Header().Set("X-Upstream-Host", req.Host)
+		w.Header().Set("X-Upstream-Path", req.URL.RequestURI())
+		w.Header().Set("X-Upstream-Method", req.Method)
+		w.Header().Set("X-Upstream-Key", req.Header.Get("X-Api-Key"))
+		w.Header().Set("X-Upstream-Client", req.Header.Get("X-Client"))
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestServeHTTP_ProxyUnmatched(t *testing.T) {
+	upstream := newEchoUpstream(t)
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	router := NewRouter()
+	router.Proxy = &ProxyConfig{
+		Target:      upstream.URL + "/api",
+		RewriteHost: true,
+		Headers:     map[string]string{"X-Api-Key": "secret"},
+	}
+	router.AddRoute(&Route{Path: "/mocked", Method: "GET", StatusCode: http.StatusOK, BodyRaw: "local"})
+
+	// Matched routes are still served locally.
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/mocked", http.NoBody))
+	if rr.Body.String() != "local" {
+		t.Errorf("Mocked route was not served locally: got %v", rr.Body.String())
+	}
+
+	req := httptest.NewRequest("POST", "/orders?page=2", strings.NewReader(`{"item": "book"}`))
+	req.Header.Set("X-Client", "test")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	expectedHeaders := map[string]string{
+		"X-Upstream-Host":   upstreamURL.Host,
+		"X-Upstream-Path":   "/api/orders?page=2",
+		"X-Upstream-Method": "POST",
+		"X-Upstream-Key":    "secret",
+		"X-Upstream-Client": "test",
+	}
+	for name, expected := range expectedHeaders {
+		if got := rr.Header().Get(name); got != expected {
+			t.Errorf("%s does not match: got %v want %v", name, got, expected)
+		}
+	}
+	if rr.Body.String() != `{"item": "book"}` {
+		t.Errorf("Body not forwarded intact: got %v", rr.Body.String())
+	}
+}
+
+func TestServeHTTP_ProxyRoute(t *testing.T) {
+	upstream := newEchoUpstream(t)
+
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/users/**", Method: "GET", ProxyTo: upstream.URL})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/1", http.NoBody))
+	if rr.Code != http.StatusAccepted || rr.Header().Get("X-Upstream-Path") != "/users/1" {
+		t.Errorf("Route was not proxied: got %v %v", rr.Code, rr.Header())
+	}
+	if host := rr.Header().Get("X-Upstream-Host"); host != "example.com" {
+		t.Errorf("Host should be kept without rewriting: got %v", host)
+	}
+
+	// Without a global upstream, other requests still get a 404.
+	if status := serveStatus(router, "GET", "/orders"); status != http.StatusNotFound {
+		t.Errorf("Unmatched request returned wrong status code: got %v", status)
+	}
+}
+
+func TestServeHTTP_ProxyTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer upstream.Close()
+
+	router := NewRouter()
+	router.Proxy = &ProxyConfig{Target: upstream.URL, Timeout: 20 * time.Millisecond}
+
+	if status := serveStatus(router, "GET", "/slow"); status != http.StatusGatewayTimeout {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusGatewayTimeout)
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidProxyTo(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "proxy_to": "upstream:8080"}]`))
+	if err == nil {
+		t.Errorf("Expected error for a relative proxy_to")
+	}
+}
+
+func TestProxyConfigValidate(t *testing.T) {
+	for target, valid := range map[string]bool{
+		"":                      true,
+		"http://localhost:9000": true,
+		"https://api.example":   true,
+		"localhost:9000":        false,
+		"/upstream":             false,
+	} {
+		err := (&ProxyConfig{Target: target}).Validate()
+		if (err == nil) != valid {
+			t.Errorf("Validate(%q) returned %v", target, err)
+		}
+	}
+}
+
+func TestLoadRoutesFromJSON_ProxyWithoutTarget(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	router := NewRouter()
+	if err := router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "proxy": true}]`)); err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if !strings.Contains(logs.String(), "no -proxy-to target") {
+		t.Errorf("Expected a warning about the missing proxy target, got %q", logs.String())
+	}
+
+	logs.Reset()
+	router = NewRouter()
+	router.Proxy = &ProxyConfig{Target: "http://localhost:9000"}
+	if err := router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "proxy": true}]`)); err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Unexpected warning: %q", logs.String())
+	}
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// recordPath is where the recorder's own state can be read and changed.
+const recordPath = AdminPrefix + "record"
+
+// volatileHeaders change from one response to the next or are recomputed
+// when the route is served, so they are left out of recorded routes.
+var volatileHeaders = []string{"Date", "Set-Cookie", "Content-Length", "Transfer-Encoding", "Connection", "Keep-Alive"}
+
+// Recorder forwards requests to Target and writes every new method, path and
+// query combination to Dir as a routes file. Combinations already recorded,
+// including those found in Dir at start, are answered by Router instead.
+type Recorder struct {
+	Router *Router
+	Target string
+	Dir    string
+
+	replayOnly atomic.Bool
+
+	mu       sync.Mutex
+	recorded map[string]bool
+}
+
+// NewRecorder loads the routes already in dir into router and returns a
+// recorder that adds to them. Without a target it only replays.
+func NewRecorder(router *Router, target, dir string) (*Recorder, error) {
+	if target != "" {
+		if err := validateProxyURL(target); err != nil {
+			return nil, fmt.Errorf("invalid record target: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := router.LoadRoutesFromDir(dir); err != nil {
+		return nil, err
+	}
+
+	rec := &Recorder{
+		Router:   router,
+		Target:   target,
+		Dir:      dir,
+		recorded: make(map[string]bool),
+	}
+	for _, route := range router.RouteList() {
+		rec.recorded[recordedKey(route)] = true
+	}
+	rec.replayOnly.Store(target == "")
+	return rec, nil
+}
+
+// SetReplayOnly stops or resumes forwarding. While replaying only, requests
+// that were not recorded get the router's 404.
+func (rec *Recorder) SetReplayOnly(replayOnly bool) {
+	if rec.Target == "" {
+		replayOnly = true
+	}
+	rec.replayOnly.Store(replayOnly)
+}
+
+// ReplayOnly reports whether forwarding is stopped.
+func (rec *Recorder) ReplayOnly() bool {
+	return rec.replayOnly.Load()
+}
+
+func (rec *Recorder) isRecorded(key string) bool {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.recorded[key]
+}
+
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == recordPath {
+		rec.serveRecord(w, req)
+		return
+	}
+
+	key := recordKey(req.Method, req.URL.Path, req.URL.Query())
+	if rec.ReplayOnly() || strings.HasPrefix(req.URL.Path, AdminPrefix) || rec.isRecorded(key) {
+		rec.Router.ServeHTTP(w, req)
+		return
+	}
+
+	handler := rec.Router.reverseProxy(rec.Target, func(resp *http.Response) error {
+		return rec.save(key, req, resp)
+	})
+	handler.ServeHTTP(w, req)
+}
+
+// save writes the upstream response for req to a routes file and adds the
+// route, so the next identical request is replayed. The body is read in full
+// and put back for the client.
+func (rec *Recorder) save(key string, req *http.Request, resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	// An identical request may have been forwarded at the same time.
+	if rec.recorded[key] {
+		return nil
+	}
+
+	route := recordedRoute(req, resp, body)
+	path, err := rec.writeRoute(route)
+	if err != nil {
+		// The client still gets the upstream response.
+		log.Printf("Failed to record %s: %v", key, err)
+		return nil
+	}
+	rec.recorded[key] = true
+	rec.Router.AddRoute(route)
+	log.Printf("Recorded %s to %s", key, path)
+	return nil
+}
+
+// writeRoute writes route to a new file in the recorder's directory and
+// returns its path.
+func (rec *Recorder) writeRoute(route *Route) (string, error) {
+	name := recordFileName(route)
+	path := filepath.Join(rec.Dir, name+".json")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(rec.Dir, fmt.Sprintf("%s-%d.json", name, i))
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode([]*Route{route}); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// recordedRoute turns an upstream exchange into a route. The query is kept as
+// match conditions; text bodies are stored as body_raw and anything else as
+// body_base64.
+func recordedRoute(req *http.Request, resp *http.Response, body []byte) *Route {
+	route := &Route{
+		Path:            req.URL.Path,
+		Method:          req.Method,
+		StatusCode:      resp.StatusCode,
+		ResponseHeaders: stableHeaders(resp.Header),
+		Match:           queryMatch(req.URL.Query()),
+	}
+	route.BodyRaw, route.BodyBase64 = encodeBody(body)
+	return route
+}
+
+// stableHeaders flattens header for a route, leaving out volatileHeaders.
+func stableHeaders(header http.Header) map[string]string {
+	var headers map[string]string
+	for name, values := range header {
+		if isVolatileHeader(name) {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+func isVolatileHeader(name string) bool {
+	for _, volatile := range volatileHeaders {
+		if strings.EqualFold(name, volatile) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryMatch turns a recorded query into match conditions, or nil if there
+// is none. A parameter sent more than once must have all its values again.
+func queryMatch(query url.Values) *RequestMatch {
+	if len(query) == 0 {
+		return nil
+	}
+	match := &RequestMatch{Query: make(map[string]ValueMatch, len(query))}
+	for name, values := range query {
+		if len(values) > 1 {
+			match.Query[name] = ValueMatch{Values: sortedValues(values)}
+		} else if values[0] == "" {
+			match.Query[name] = ValueMatch{Present: true}
+		} else {
+			match.Query[name] = ValueMatch{Equals: values[0]}
+		}
+	}
+	return match
+}
+
+// encodeBody returns a text body as raw and anything else as base64.
+func encodeBody(body []byte) (raw, encoded string) {
+	switch {
+	case len(body) == 0:
+		return "", ""
+	case utf8.Valid(body):
+		return string(body), ""
+	default:
+		return "", base64.StdEncoding.EncodeToString(body)
+	}
+}
+
+// recordKey identifies an exchange by its method, path and query. The values
+// of a repeated parameter are sorted, so their order does not matter.
+func recordKey(method, path string, query url.Values) string {
+	key := routeKey(method, path)
+	if len(query) > 0 {
+		sorted := make(url.Values, len(query))
+		for name, values := range query {
+			sorted[name] = sortedValues(values)
+		}
+		key += "?" + sorted.Encode()
+	}
+	return key
+}
+
+// recordedKey is recordKey for a route read back from a recorded file.
+func recordedKey(route *Route) string {
+	query := url.Values{}
+	if route.Match != nil {
+		for name, vm := range route.Match.Query {
+			if len(vm.Values) > 0 {
+				query[name] = vm.Values
+			} else {
+				query.Set(name, vm.Equals)
+			}
+		}
+	}
+	return recordKey(route.Method, route.Path, query)
+}
+
+// recordFileName builds a readable file name from the route's method and
+// path. A hash of the query tells apart exchanges that differ only there.
+func recordFileName(route *Route) string {
+	path := strings.Map(func(c rune) rune {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' {
+			return c
+		}
+		return '_'
+	}, strings.Trim(route.Path, "/"))
+	if path == "" {
+		path = "root"
+	}
+	if len(path) > 80 {
+		path = path[:80]
+	}
+
+	name := strings.ToUpper(route.Method) + "_" + path
+	if route.Match != nil && len(route.Match.Query) > 0 {
+		sum := sha1.Sum([]byte(recordedKey(route)))
+		name += "_" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+type recordState struct {
+	Target     string `json:"target"`
+	ReplayOnly bool   `json:"replay_only"`
+	Recorded   int    `json:"recorded"`
+}
+
+// serveRecord shows the recorder's state on GET and switches replay-only mode
+// on PUT.
+func (rec *Recorder) serveRecord(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var update struct {
+			ReplayOnly *bool `json:"replay_only"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&update); err != nil || update.ReplayOnly == nil {
+			http.Error(w, "Expected a JSON body with replay_only", http.StatusBadRequest)
+			return
+		}
+		rec.SetReplayOnly(*update.ReplayOnly)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut)
+		return
+	}
+
+	rec.mu.Lock()
+	state := recordState{Target: rec.Target, ReplayOnly: rec.ReplayOnly(), Recorded: len(rec.recorded)}
+	rec.mu.Unlock()
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newRecordUpstream starts an upstream that counts the requests it answers.
+func newRecordUpstream(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("X-Page", req.URL.Query().Get("page"))
+		w.Header().Set("X-Query", req.URL.RawQuery)
+		if req.URL.Path == "/logo.png" {
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', 0xff, 0xfe})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"path": "` + req.URL.Path + `", "page": "` + req.URL.Query().Get("page") + `"}`))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func serveRecorder(rec *Recorder, method, target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	rec.ServeHTTP(rr, httptest.NewRequest(method, target, http.NoBody))
+	return rr
+}
+
+func TestRecorder(t *testing.T) {
+	var hits int32
+	upstream := newRecordUpstream(t, &hits)
+	dir := t.TempDir()
+
+	rec, err := NewRecorder(NewRouter(), upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	for _, target := range []string{"/users/1?page=2", "/users/1?page=2", "/users/1", "/logo.png"} {
+		if rr := serveRecorder(rec, "GET", target); rr.Body.Len() == 0 {
+			t.Errorf("%s: upstream body was not passed on", target)
+		}
+	}
+	if hits != 3 {
+		t.Errorf("Upstream should be hit once per unique request: got %v want 3", hits)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 recorded files, got %v", len(files))
+	}
+
+	// The recorded files load back and replay the exchanges.
+	router := NewRouter()
+	if err := router.LoadRoutesFromDir(dir); err != nil {
+		t.Fatalf("Recorded routes failed to load: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/1?page=2", http.NoBody))
+	if rr.Code != http.StatusCreated || rr.Body.String() != `{"path": "/users/1", "page": "2"}` {
+		t.Errorf("Replay returned %v %v", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Page") != "2" || rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Recorded headers missing: %v", rr.Header())
+	}
+	if rr.Header().Get("Set-Cookie") != "" || rr.Header().Get("Date") != "" {
+		t.Errorf("Volatile headers should not be recorded: %v", rr.Header())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/users/1", http.NoBody))
+	if rr.Header().Get("X-Page") != "" {
+		t.Errorf("Request without query replayed the wrong exchange: %v", rr.Header())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/logo.png", http.NoBody))
+	if rr.Body.String() != string([]byte{0x89, 'P', 'N', 'G', 0xff, 0xfe}) {
+		t.Errorf("Binary body was not replayed intact: %q", rr.Body.String())
+	}
+}
+
+func TestRecorder_ReplayOnly(t *testing.T) {
+	var hits int32
+	upstream := newRecordUpstream(t, &hits)
+	dir := t.TempDir()
+
+	rec, err := NewRecorder(NewRouter(), upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	serveRecorder(rec, "GET", "/known")
+
+	rr := httptest.NewRecorder()
+	rec.ServeHTTP(rr, httptest.NewRequest("PUT", "/__faux/record", strings.NewReader(`{"replay_only": true}`)))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"replay_only":true`) {
+		t.Errorf("Switching to replay-only returned %v %v", rr.Code, rr.Body.String())
+	}
+
+	if rr := serveRecorder(rec, "GET", "/unknown"); rr.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr := serveRecorder(rec, "GET", "/known"); rr.Code != http.StatusCreated {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if hits != 1 {
+		t.Errorf("Replay-only mode should not forward: got %v upstream hits", hits)
+	}
+
+	// A new recorder over the same directory replays what is there.
+	rec, err = NewRecorder(NewRouter(), upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	serveRecorder(rec, "GET", "/known")
+	if hits != 1 {
+		t.Errorf("Previously recorded request was forwarded again")
+	}
+}
+
+func TestRecorder_RepeatedQuery(t *testing.T) {
+	var hits int32
+	upstream := newRecordUpstream(t, &hits)
+	dir := t.TempDir()
+
+	rec, err := NewRecorder(NewRouter(), upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	serveRecorder(rec, "GET", "/items?tag=b&tag=a")
+	serveRecorder(rec, "GET", "/items?tag=a&tag=b")
+	serveRecorder(rec, "GET", "/items?tag=a")
+	if hits != 2 {
+		t.Errorf("Upstream should be hit once per set of values: got %v want 2", hits)
+	}
+
+	// Reloading the directory recognises both exchanges.
+	rec, err = NewRecorder(NewRouter(), upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	serveRecorder(rec, "GET", "/items?tag=a&tag=b")
+	serveRecorder(rec, "GET", "/items?tag=a")
+	if hits != 2 {
+		t.Errorf("Previously recorded request was forwarded again: got %v upstream hits", hits)
+	}
+	for target, expected := range map[string]string{"/items?tag=a&tag=b": "tag=b&tag=a", "/items?tag=a": "tag=a"} {
+		if rr := serveRecorder(rec, "GET", target); rr.Header().Get("X-Query") != expected {
+			t.Errorf("%s replayed the exchange for %q", target, rr.Header().Get("X-Query"))
+		}
+	}
+
+	if rr := serveRecorder(rec, "GET", "/items?tag=a&tag=c"); rr.Code != http.StatusCreated || hits != 3 {
+		t.Errorf("Different values should be forwarded: got %v with %v upstream hits", rr.Code, hits)
+	}
+}
+
+func TestRecordFileName(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		route    *Route
+		expected string
+	}{
+		{desc: "root", route: &Route{Method: "get", Path: "/"}, expected: "GET_root"},
+		{desc: "nested", route: &Route{Method: "POST", Path: "/v1/users/42"}, expected: "POST_v1_users_42"},
+		{desc: "odd characters", route: &Route{Method: "GET", Path: "/a b/c:d"}, expected: "GET_a_b_c_d"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if name := recordFileName(tC.route); name != tC.expected {
+				t.Errorf("got %v want %v", name, tC.expected)
+			}
+		})
+	}
+
+	withQuery := &Route{Method: "GET", Path: "/users", Match: &RequestMatch{Query: map[string]ValueMatch{"page": {Equals: "2"}}}}
+	if name := recordFileName(withQuery); !strings.HasPrefix(name, "GET_users_") || name == "GET_users" {
+		t.Errorf("Query should add a hash to the name: got %v", name)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"math/rand"
+	"path/filepath"
+)
+
+// Response modes for routes with several responses.
+const (
+	// ResponseModeSequence serves the responses in order and then keeps
+	// serving the last one.
+	ResponseModeSequence = "sequence"
+	// ResponseModeCycle serves the responses in order and starts over.
+	ResponseModeCycle = "cycle"
+	// ResponseModeRandomWeighted picks a response at random by weight.
+	ResponseModeRandomWeighted = "random-weighted"
+)
+
+// RouteResponse is one of the responses a route with several responses can
+// serve. Unset fields fall back to the route's own.
+type RouteResponse struct {
+	StatusCode      int               `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    interface{}       `json:"response_body,omitempty"`
+	BodyRaw         string            `json:"body_raw,omitempty"`
+	BodyBase64      string            `json:"body_base64,omitempty"`
+	BodyJSON        interface{}       `json:"body_json,omitempty"`
+	BodyFile        string            `json:"body_file,omitempty"`
+	// Weight is only used by the random-weighted mode and defaults to 1.
+	Weight int `json:"weight,omitempty"`
+}
+
+func (resp *RouteResponse) body() responseBody {
+	return responseBody{
+		Raw:    resp.BodyRaw,
+		Base64: resp.BodyBase64,
+		File:   resp.BodyFile,
+		JSON:   resp.BodyJSON,
+		Legacy: resp.ResponseBody,
+	}
+}
+
+func (resp *RouteResponse) weight() int {
+	if resp.Weight <= 0 {
+		return 1
+	}
+	return resp.Weight
+}
+
+// nextResponse returns the response to serve for this call to the route and
+// advances its counter.
+func (r *Router) nextResponse(route *Route) *RouteResponse {
+	if route.ResponseMode == ResponseModeRandomWeighted {
+		total := 0
+		for i := range route.Responses {
+			total += route.Responses[i].weight()
+		}
+		pick := rand.Intn(total)
+		for i := range route.Responses {
+			if pick < route.Responses[i].weight() {
+				return &route.Responses[i]
+			}
+			pick -= route.Responses[i].weight()
+		}
+	}
+
+	key := route.key()
+	r.stateMu.Lock()
+	call := r.calls[key]
+	r.calls[key] = call + 1
+	r.stateMu.Unlock()
+
+	if route.ResponseMode == ResponseModeCycle {
+		return &route.Responses[call%len(route.Responses)]
+	}
+	if call >= len(route.Responses) {
+		call = len(route.Responses) - 1
+	}
+	return &route.Responses[call]
+}
+
+// ResetResponses starts every sequenced and cycling route over from its first
+// response.
+func (r *Router) ResetResponses() {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	r.calls = make(map[string]int)
+}
+
+// resolveRelativePaths makes the route's relative body files and JWKS file
+// relative to baseDir.
+func (route *Route) resolveRelativePaths(baseDir string) {
+	if route.BodyFile != "" && !filepath.IsAbs(route.BodyFile) {
+		route.BodyFile = filepath.Join(baseDir, route.BodyFile)
+	}
+	if route.Auth != nil && route.Auth.JWT != nil {
+		auth, jwt := *route.Auth, *route.Auth.JWT
+		jwt.resolveJWKS(baseDir)
+		auth.JWT = &jwt
+		route.Auth = &auth
+	}
+	if len(route.Responses) == 0 {
+		return
+	}
+
+	responses := make([]RouteResponse, len(route.Responses))
+	copy(responses, route.Responses)
+	for i := range responses {
+		if responses[i].BodyFile != "" && !filepath.IsAbs(responses[i].BodyFile) {
+			responses[i].BodyFile = filepath.Join(baseDir, responses[i].BodyFile)
+		}
+	}
+	route.Responses = responses
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func serveStatus(router *Router, method, path string) int {
+	req := httptest.NewRequest(method, path, http.NoBody)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestServeHTTP_ResponseSequence(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{
+			"path": "/flaky",
+			"method": "GET",
+			"status_code": 200,
+			"responses": [
+				{"status_code": 500},
+				{"status_code": 503},
+				{"body_raw": "ok"}
+			]
+		}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	for i, expected := range []int{500, 503, 200, 200} {
+		if status := serveStatus(router, "GET", "/flaky"); status != expected {
+			t.Errorf("Call %d: got status %v want %v", i, status, expected)
+		}
+	}
+
+	if status := serveStatus(router, "POST", AdminPrefix+"responses/reset"); status != http.StatusNoContent {
+		t.Fatalf("Reset returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+	if status := serveStatus(router, "GET", "/flaky"); status != 500 {
+		t.Errorf("Sequence not reset: got status %v want %v", status, 500)
+	}
+}
+
+func TestServeHTTP_ResponseCycleConcurrent(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:         "/cycle",
+		Method:       "GET",
+		StatusCode:   200,
+		ResponseMode: ResponseModeCycle,
+		Responses:    []RouteResponse{{StatusCode: 200}, {StatusCode: 202}},
+	})
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		counts = make(map[int]int)
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := serveStatus(router, "GET", "/cycle")
+			mu.Lock()
+			counts[status]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if counts[200] != 50 || counts[202] != 50 {
+		t.Errorf("Expected an even split between responses, got %v", counts)
+	}
+}
+
+func TestServeHTTP_ResponseRandomWeighted(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{
+		Path:         "/weighted",
+		Method:       "GET",
+		StatusCode:   200,
+		ResponseMode: ResponseModeRandomWeighted,
+		Responses:    []RouteResponse{{StatusCode: 200, Weight: 3}, {StatusCode: 500}},
+	})
+
+	for i := 0; i < 20; i++ {
+		if status := serveStatus(router, "GET", "/weighted"); status != 200 && status != 500 {
+			t.Fatalf("Unexpected status code %v", status)
+		}
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidResponseMode(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "status_code": 200, "response_mode": "shuffle", "responses": [{"status_code": 200}]}]`))
+	if err == nil {
+		t.Errorf("Expected error for unknown response_mode")
+	}
+}
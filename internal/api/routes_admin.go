@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RouteList returns every route, ordered by method and path.
+func (r *Router) RouteList() []*Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make([]*Route, 0, len(r.Routes))
+	for _, route := range r.Routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].key() < routes[j].key()
+	})
+	return routes
+}
+
+// RouteByID returns the route with the given ID.
+func (r *Router) RouteByID(id string) (*Route, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keyByID(id)
+	if !ok {
+		return nil, false
+	}
+	return r.Routes[key], true
+}
+
+func (r *Router) keyByID(id string) (string, bool) {
+	for key, route := range r.Routes {
+		if route.ID == id {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// AddDynamicRoute adds a route at runtime. Unlike AddRoute, the change is
+// undone by ResetRoutes. It fails if the route's ID belongs to a route with
+// another method, path or conditions.
+func (r *Router) AddDynamicRoute(route *Route) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if route.ID != "" && r.idTaken(route.ID, route.key()) {
+		return fmt.Errorf("route id %s is already in use", route.ID)
+	}
+	r.addDynamicRoute(route)
+	return nil
+}
+
+func (r *Router) addDynamicRoute(route *Route) {
+	// The replaced route is removed even when route took over its ID, so a
+	// loaded route is kept aside for ResetRoutes.
+	if replaced := r.addRoute(route); replaced != nil {
+		r.remove(replaced)
+	}
+	r.dynamic[route.ID] = true
+}
+
+// idTaken reports whether a route other than the one under key, served or
+// kept aside, has the ID.
+func (r *Router) idTaken(id, key string) bool {
+	if found, ok := r.keyByID(id); ok && found != key {
+		return true
+	}
+	for _, route := range r.stashed {
+		if route.ID == id && route.key() != key {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceRoute swaps the route with the given ID for a new definition that
+// keeps the same ID. It reports false if there is no such route.
+func (r *Router) ReplaceRoute(id string, route *Route) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keyByID(id)
+	if !ok {
+		return false
+	}
+	r.remove(r.Routes[key])
+	delete(r.Routes, key)
+
+	route.ID = id
+	r.addDynamicRoute(route)
+	return true
+}
+
+// DeleteRoute removes the route with the given ID. It reports false if there
+// is no such route.
+func (r *Router) DeleteRoute(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keyByID(id)
+	if !ok {
+		return false
+	}
+	r.remove(r.Routes[key])
+	delete(r.Routes, key)
+	return true
+}
+
+// remove forgets a route that is being taken out by the admin API, keeping
+// loaded routes aside so ResetRoutes can bring them back.
+func (r *Router) remove(route *Route) {
+	if r.dynamic[route.ID] {
+		delete(r.dynamic, route.ID)
+		return
+	}
+	r.stashed = append(r.stashed, route)
+}
+
+// ResetRoutes undoes every change made through the admin API, leaving the
+// routes as they were loaded.
+func (r *Router) ResetRoutes() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, route := range r.Routes {
+		if r.dynamic[route.ID] {
+			delete(r.Routes, key)
+		}
+	}
+	for _, route := range r.stashed {
+		r.Routes[route.key()] = route
+	}
+	r.dynamic = make(map[string]bool)
+	r.stashed = nil
+}
+
+func (r *Router) serveRoutes(w http.ResponseWriter, req *http.Request) {
+	id := strings.TrimPrefix(req.URL.Path, AdminPrefix+"routes")
+	id = strings.TrimPrefix(id, "/")
+
+	switch {
+	case id == "" && req.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, r.RouteList())
+	case id == "" && req.Method == http.MethodPost:
+		route, ok := r.decodeRoute(w, req)
+		if !ok {
+			return
+		}
+		if err := r.AddDynamicRoute(route); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, http.StatusCreated, route)
+	case id == "":
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	case id == "reset" && req.Method == http.MethodPost:
+		r.ResetRoutes()
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodGet:
+		route, ok := r.RouteByID(id)
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		writeJSON(w, http.StatusOK, route)
+	case req.Method == http.MethodPut:
+		route, ok := r.decodeRoute(w, req)
+		if !ok {
+			return
+		}
+		if !r.ReplaceRoute(id, route) {
+			http.NotFound(w, req)
+			return
+		}
+		writeJSON(w, http.StatusOK, route)
+	case req.Method == http.MethodDelete:
+		if !r.DeleteRoute(id) {
+			http.NotFound(w, req)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// readsFiles reports whether the route reads a body or JWT keys from disk.
+func (route *Route) readsFiles() bool {
+	if route.BodyFile != "" {
+		return true
+	}
+	for _, resp := range route.Responses {
+		if resp.BodyFile != "" {
+			return true
+		}
+	}
+	return route.Auth != nil && route.Auth.JWT != nil && route.Auth.JWT.JWKS != ""
+}
+
+// decodeRoute reads a route definition from the request body, answering with
+// 400 if it is not valid.
+func (r *Router) decodeRoute(w http.ResponseWriter, req *http.Request) (*Route, bool) {
+	var route Route
+	if err := json.NewDecoder(req.Body).Decode(&route); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return nil, false
+	}
+	if route.Path == "" || route.Method == "" {
+		http.Error(w, "A route needs a path and a method", http.StatusBadRequest)
+		return nil, false
+	}
+	// Anyone who can reach the admin API could otherwise read any file on
+	// the host.
+	if route.readsFiles() {
+		http.Error(w, "body_file and jwks cannot be set through the admin API", http.StatusBadRequest)
+		return nil, false
+	}
+	if err := r.validateRoute(&route); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &route, true
+}
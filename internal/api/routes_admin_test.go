@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func adminRequest(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminRoutes(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/loaded", Method: "GET", StatusCode: http.StatusOK})
+
+	// Add a route.
+	rr := adminRequest(t, router, "POST", AdminPrefix+"routes", `{"path": "/stub", "method": "GET", "status_code": 202}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	var created Route
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || created.ID == "" {
+		t.Fatalf("POST did not return the route with an ID: %v", rr.Body.String())
+	}
+	if status := serveStatus(router, "GET", "/stub"); status != http.StatusAccepted {
+		t.Errorf("Added route returned wrong status code: got %v", status)
+	}
+
+	// List routes.
+	rr = adminRequest(t, router, "GET", AdminPrefix+"routes", "")
+	var routes []Route
+	if err := json.Unmarshal(rr.Body.Bytes(), &routes); err != nil || len(routes) != 2 {
+		t.Fatalf("Expected two routes, got %v", rr.Body.String())
+	}
+
+	// Replace it.
+	rr = adminRequest(t, router, "PUT", AdminPrefix+"routes/"+created.ID, `{"path": "/stub", "method": "GET", "status_code": 418}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT returned wrong status code: got %v", rr.Code)
+	}
+	if status := serveStatus(router, "GET", "/stub"); status != http.StatusTeapot {
+		t.Errorf("Replaced route returned wrong status code: got %v", status)
+	}
+
+	// Delete the loaded route.
+	loaded := routes[0]
+	if rr = adminRequest(t, router, "DELETE", AdminPrefix+"routes/"+loaded.ID, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE returned wrong status code: got %v", rr.Code)
+	}
+	if status := serveStatus(router, "GET", "/loaded"); status != http.StatusNotFound {
+		t.Errorf("Deleted route still served: got %v", status)
+	}
+	if rr = adminRequest(t, router, "DELETE", AdminPrefix+"routes/"+loaded.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Second DELETE returned wrong status code: got %v", rr.Code)
+	}
+
+	// Reset brings back the loaded route and drops the added one.
+	if rr = adminRequest(t, router, "POST", AdminPrefix+"routes/reset", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Reset returned wrong status code: got %v", rr.Code)
+	}
+	if status := serveStatus(router, "GET", "/loaded"); status != http.StatusOK {
+		t.Errorf("Loaded route not restored: got %v", status)
+	}
+	if status := serveStatus(router, "GET", "/stub"); status != http.StatusNotFound {
+		t.Errorf("Added route not removed: got %v", status)
+	}
+}
+
+func TestAdminRoutes_OverrideAndReset(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/a", Method: "GET", StatusCode: http.StatusOK})
+
+	rr := adminRequest(t, router, "POST", AdminPrefix+"routes", `{"path": "/a", "method": "GET", "status_code": 503}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if status := serveStatus(router, "GET", "/a"); status != http.StatusServiceUnavailable {
+		t.Errorf("Override returned wrong status code: got %v", status)
+	}
+
+	if rr = adminRequest(t, router, "POST", AdminPrefix+"routes/reset", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Reset returned wrong status code: got %v", rr.Code)
+	}
+	if status := serveStatus(router, "GET", "/a"); status != http.StatusOK {
+		t.Errorf("Loaded route not restored: got %v", status)
+	}
+	if len(router.Routes) != 1 {
+		t.Errorf("Expected one route after reset, got %v", len(router.Routes))
+	}
+}
+
+func TestAdminRoutes_DuplicateID(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{ID: "taken", Path: "/a", Method: "GET", StatusCode: http.StatusOK})
+
+	rr := adminRequest(t, router, "POST", AdminPrefix+"routes", `{"id": "taken", "path": "/b", "method": "GET", "status_code": 200}`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+	}
+	if status := serveStatus(router, "GET", "/b"); status != http.StatusNotFound {
+		t.Errorf("Route with a duplicate ID was added: got %v", status)
+	}
+
+	// The ID may be reused by a route that replaces its owner.
+	rr = adminRequest(t, router, "POST", AdminPrefix+"routes", `{"id": "taken", "path": "/a", "method": "GET", "status_code": 202}`)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+}
+
+func TestAdminRoutes_InvalidRoute(t *testing.T) {
+	router := NewRouter()
+
+	rr := adminRequest(t, router, "POST", AdminPrefix+"routes", `{"path": "/stub"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = adminRequest(t, router, "POST", AdminPrefix+"routes", `{"path": "/stub", "method": "GET", "body_template": "{{"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	for _, route := range []string{
+		`{"path": "/stub", "method": "GET", "status_code": 42}`,
+		`{"path": "/stub", "method": "GET", "status_code": 600}`,
+		`{"path": "/stub", "method": "GET", "responses": [{"status_code": 1000}]}`,
+		`{"path": "/stub", "method": "GET", "throttling_low": 50, "throttling_hi": 10}`,
+		`{"path": "/stub", "method": "GET", "throttling_low": -1}`,
+		`{"path": "/stub", "method": "GET", "rate_limit_per_min": 0.5}`,
+		`{"path": "/stub", "method": "GET", "body_file": "/etc/passwd"}`,
+		`{"path": "/stub", "method": "GET", "responses": [{"body_file": "../secrets.json"}]}`,
+		`{"path": "/stub", "method": "GET", "auth": {"jwt": {"jwks": "/etc/hostname"}}}`,
+	} {
+		rr = adminRequest(t, router, "POST", AdminPrefix+"routes", route)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %v want %v", route, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAdminRoutes_DefaultStatus(t *testing.T) {
+	router := NewRouter()
+
+	rr := adminRequest(t, router, "POST", AdminPrefix+"routes", `{"path": "/x", "method": "GET"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("POST returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if status := serveStatus(router, "GET", "/x"); status != http.StatusOK {
+		t.Errorf("Route without a status returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestAdminRoutes_Auth(t *testing.T) {
+	router := NewRouter()
+	middleware := &AuthMiddleware{Token: "mytoken", Next: router}
+
+	rr := adminRequest(t, middleware, "GET", AdminPrefix+"routes", "")
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+
+	req := httptest.NewRequest("GET", AdminPrefix+"routes", http.NoBody)
+	req.Header.Set("Authorization", "mytoken")
+	rr = httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestAdminRoutes_Concurrent(t *testing.T) {
+	router := NewRouter()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			adminRequest(t, router, "POST", AdminPrefix+"routes", `{"path": "/stub", "method": "GET", "status_code": 200}`)
+		}()
+		go func() {
+			defer wg.Done()
+			serveStatus(router, "GET", "/stub")
+		}()
+	}
+	wg.Wait()
+
+	if len(router.RouteList()) != 1 {
+		t.Errorf("Expected one route, got %v", len(router.RouteList()))
+	}
+}
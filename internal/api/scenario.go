@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ScenarioStarted is the state every scenario begins in.
+const ScenarioStarted = "Started"
+
+// scenarioState returns the current state of the named scenario.
+func (r *Router) scenarioState(name string) string {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	if state, ok := r.scenarios[name]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+// SetScenarioState moves the named scenario to a new state.
+func (r *Router) SetScenarioState(name, state string) {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	r.scenarios[name] = state
+}
+
+// Scenarios returns the current state of every scenario used by a route or
+// set explicitly.
+func (r *Router) Scenarios() map[string]string {
+	states := make(map[string]string)
+	for _, route := range r.RouteList() {
+		if route.Scenario != "" {
+			states[route.Scenario] = ScenarioStarted
+		}
+	}
+
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	for name, state := range r.scenarios {
+		states[name] = state
+	}
+	return states
+}
+
+// ResetScenarios moves every scenario back to ScenarioStarted.
+func (r *Router) ResetScenarios() {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	r.scenarios = make(map[string]string)
+}
+
+// inRequiredState reports whether the route's scenario allows it to match.
+func (r *Router) inRequiredState(route *Route) bool {
+	return route.RequiredState == "" || r.scenarioState(route.Scenario) == route.RequiredState
+}
+
+// enterScenario applies the route's state transition once it has been
+// chosen. The required state is checked again under the same lock, so two
+// requests cannot both make one transition; false means another request
+// moved the scenario on since the route was chosen.
+func (r *Router) enterScenario(route *Route) bool {
+	if route.Scenario == "" {
+		return true
+	}
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	if route.RequiredState != "" {
+		state, ok := r.scenarios[route.Scenario]
+		if !ok {
+			state = ScenarioStarted
+		}
+		if state != route.RequiredState {
+			return false
+		}
+	}
+	if route.NewState != "" {
+		r.scenarios[route.Scenario] = route.NewState
+	}
+	return true
+}
+
+func (r *Router) serveScenarios(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, AdminPrefix+"scenarios")
+	name = strings.TrimPrefix(name, "/")
+
+	switch {
+	case name == "" && req.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, r.Scenarios())
+	case name == "":
+		methodNotAllowed(w, http.MethodGet)
+	case name == "reset" && req.Method == http.MethodPost:
+		r.ResetScenarios()
+		w.WriteHeader(http.StatusNoContent)
+	case req.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"name": name, "state": r.scenarioState(name)})
+	case req.Method == http.MethodPut:
+		var body struct {
+			State string `json:"state"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.State == "" {
+			http.Error(w, "Expected a JSON body with a state", http.StatusBadRequest)
+			return
+		}
+		r.SetScenarioState(name, body.State)
+		writeJSON(w, http.StatusOK, map[string]string{"name": name, "state": body.State})
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut)
+	}
+}
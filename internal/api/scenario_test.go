@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServeHTTP_Scenario(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{
+			"path": "/orders/1",
+			"method": "GET",
+			"status_code": 200,
+			"body_raw": "pending",
+			"scenario": "order",
+			"required_state": "Started"
+		},
+		{
+			"path": "/orders/1",
+			"method": "PATCH",
+			"status_code": 204,
+			"scenario": "order",
+			"new_state": "Shipped"
+		},
+		{
+			"path": "/orders/1",
+			"method": "GET",
+			"status_code": 200,
+			"body_raw": "shipped",
+			"scenario": "order",
+			"required_state": "Shipped"
+		}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	get := func() string {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/orders/1", http.NoBody))
+		return rr.Body.String()
+	}
+
+	if body := get(); body != "pending" {
+		t.Errorf("Expected pending before the transition, got %v", body)
+	}
+
+	if status := serveStatus(router, "PATCH", "/orders/1"); status != http.StatusNoContent {
+		t.Fatalf("PATCH returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+	if body := get(); body != "shipped" {
+		t.Errorf("Expected shipped after the transition, got %v", body)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", AdminPrefix+"scenarios", http.NoBody))
+	var states map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &states); err != nil {
+		t.Fatalf("Failed to decode scenarios: %v", err)
+	}
+	if states["order"] != "Shipped" {
+		t.Errorf("Expected scenario state Shipped, got %v", states)
+	}
+
+	if status := serveStatus(router, "POST", AdminPrefix+"scenarios/reset"); status != http.StatusNoContent {
+		t.Fatalf("Reset returned wrong status code: got %v", status)
+	}
+	if body := get(); body != "pending" {
+		t.Errorf("Expected pending after reset, got %v", body)
+	}
+
+	req := httptest.NewRequest("PUT", AdminPrefix+"scenarios/order", strings.NewReader(`{"state": "Shipped"}`))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT returned wrong status code: got %v", rr.Code)
+	}
+	if body := get(); body != "shipped" {
+		t.Errorf("Expected shipped after setting the state, got %v", body)
+	}
+}
+
+func TestServeHTTP_ScenarioConcurrentTransition(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{"path": "/coupon", "method": "POST", "status_code": 410},
+		{"path": "/coupon", "method": "POST", "status_code": 200, "scenario": "coupon", "required_state": "Started", "new_state": "Redeemed"}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	for _, handler := range []http.Handler{router, &AuthMiddleware{Next: router}} {
+		router.ResetScenarios()
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			redeemed int
+		)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest("POST", "/coupon", http.NoBody))
+				if rr.Code == http.StatusOK {
+					mu.Lock()
+					redeemed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if redeemed != 1 {
+			t.Errorf("Expected the transition to happen once, got %v", redeemed)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+)
+
+// specVersion holds the top-level field that tells the kind of a spec apart.
+type specVersion struct {
+	OpenAPI  string `json:"openapi"`
+	Swagger  string `json:"swagger"`
+	AsyncAPI string `json:"asyncapi"`
+}
+
+// specVersionOf reads the version fields of a JSON or YAML document. A
+// document that cannot be read has none, and is left to the OpenAPI loader to
+// report.
+func specVersionOf(data []byte) specVersion {
+	var version specVersion
+	_ = yaml.Unmarshal(data, &version)
+	return version
+}
+
+// convertSwagger reads a Swagger 2.0 document and converts it to OpenAPI 3.
+// Response examples, which the conversion drops, are carried over.
+func convertSwagger(data []byte) (*openapi3.T, error) {
+	var doc2 openapi2.T
+	if err := yaml.Unmarshal(data, &doc2); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc2.Swagger, "2.") {
+		return nil, fmt.Errorf("unsupported swagger version %q", doc2.Swagger)
+	}
+
+	doc3, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("converting swagger %s: %w", doc2.Swagger, err)
+	}
+	if err := openapi3.NewLoader().ResolveRefsIn(doc3, nil); err != nil {
+		return nil, err
+	}
+	copySwaggerExamples(&doc2, doc3)
+	return doc3, nil
+}
+
+// copySwaggerExamples sets the examples of each Swagger response, keyed by
+// MIME type, as the examples of the converted response's media types.
+func copySwaggerExamples(doc2 *openapi2.T, doc3 *openapi3.T) {
+	for path, pathItem2 := range doc2.Paths {
+		pathItem3 := doc3.Paths[path]
+		if pathItem2 == nil || pathItem3 == nil {
+			continue
+		}
+		for method, operation2 := range pathItem2.Operations() {
+			operation3 := pathItem3.GetOperation(method)
+			if operation3 == nil {
+				continue
+			}
+			for code, response2 := range operation2.Responses {
+				if response2 != nil && response2.Ref != "" {
+					response2 = doc2.Responses[strings.TrimPrefix(response2.Ref, "#/responses/")]
+				}
+				ref := operation3.Responses[code]
+				if response2 == nil || len(response2.Examples) == 0 || ref == nil || ref.Value == nil {
+					continue
+				}
+				response3 := ref.Value
+				if response3.Content == nil {
+					response3.Content = make(openapi3.Content)
+				}
+				for mimeType, example := range response2.Examples {
+					mediaType := response3.Content[mimeType]
+					if mediaType == nil {
+						mediaType = openapi3.NewMediaType()
+						response3.Content[mimeType] = mediaType
+					}
+					mediaType.Example = example
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockSwagger = `
+swagger: "2.0"
+info: {title: Pets, version: "1"}
+basePath: /v1
+produces: [application/json]
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, type: integer}
+      responses:
+        200:
+          description: A pet
+          schema: {$ref: '#/definitions/Pet'}
+          examples:
+            application/json: {id: 7, name: Rex}
+        404:
+          $ref: '#/responses/NotFound'
+    delete:
+      parameters:
+        - {name: id, in: path, required: true, type: integer}
+      responses:
+        204: {description: Deleted}
+  /pets:
+    get:
+      responses:
+        200:
+          description: Pets
+          schema:
+            type: array
+            items: {$ref: '#/definitions/Pet'}
+responses:
+  NotFound:
+    description: Not found
+    schema: {type: object, properties: {error: {type: string}}}
+    examples:
+      application/json: {error: no such pet}
+definitions:
+  Pet:
+    type: object
+    required: [id, name]
+    properties:
+      id: {type: integer}
+      name: {type: string}
+`
+
+func TestParseOpenAPIFromText_Swagger(t *testing.T) {
+	spec, err := ParseOpenAPIFromText(mockSwagger)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	routes, err := MapOpenAPIRoutes(spec)
+	assert.NoError(t, err)
+	assert.Len(t, routes, 3)
+}
+
+func TestLoadRoutesFromOpenAPI_Swagger(t *testing.T) {
+	router := NewRouter()
+	assert.NoError(t, router.LoadRoutesFromOpenAPI(mockSwagger))
+
+	testCases := []struct {
+		desc       string
+		method     string
+		path       string
+		prefer     string
+		wantStatus int
+		wantBody   string
+	}{
+		{desc: "response example", method: "GET", path: "/pets/7", wantStatus: http.StatusOK, wantBody: `{"id": 7, "name": "Rex"}`},
+		{desc: "example of a shared response", method: "GET", path: "/pets/7", prefer: "code=404", wantStatus: http.StatusNotFound, wantBody: `{"error": "no such pet"}`},
+		{desc: "generated from a referenced schema", method: "GET", path: "/pets", wantStatus: http.StatusOK, wantBody: `[{"id": 0, "name": "string"}]`},
+		{desc: "no content", method: "DELETE", path: "/pets/7", wantStatus: http.StatusNoContent},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(tC.method, tC.path, http.NoBody)
+			if tC.prefer != "" {
+				req.Header.Set("Prefer", tC.prefer)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, tC.wantStatus, rr.Code)
+			if tC.wantBody != "" {
+				assert.JSONEq(t, tC.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+)
+
+// templateData is what body and header templates can see of the request.
+// Query and Headers keep all values, so use {{.Query.Get "q"}} and
+// {{.Headers.Get "X-Request-Id"}} to read them. Claims holds the claims of
+// the JWT the request was let in with.
+type templateData struct {
+	Method  string
+	Path    string
+	Params  map[string]string
+	Query   url.Values
+	Headers http.Header
+	Cookies map[string]string
+	Body    interface{}
+	RawBody string
+	Claims  map[string]interface{}
+}
+
+var templateFuncs = template.FuncMap{
+	"uuid": newUUID,
+	"now": func(layout ...string) string {
+		if len(layout) > 0 {
+			return time.Now().Format(layout[0])
+		}
+		return time.Now().Format(time.RFC3339)
+	},
+	"randInt": func(low, high int) (int, error) {
+		if high < low {
+			return 0, fmt.Errorf("randInt: %d is less than %d", high, low)
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(high-low+1)))
+		if err != nil {
+			return 0, err
+		}
+		return low + int(n.Int64()), nil
+	},
+	"base64": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"base64Decode": func(s string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(s)
+		return string(data), err
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// newTemplateData collects the request details for a template. The body is
+// read and put back so later handlers can still use it.
+func newTemplateData(req *http.Request) (*templateData, error) {
+	rawBody, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &templateData{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Params:  PathParams(req),
+		Query:   req.URL.Query(),
+		Headers: req.Header,
+		Cookies: make(map[string]string),
+		RawBody: string(rawBody),
+		Claims:  JWTClaims(req),
+	}
+	for _, cookie := range req.Cookies() {
+		data.Cookies[cookie.Name] = cookie.Value
+	}
+	if len(rawBody) > 0 {
+		// A body that isn't JSON is still available as RawBody.
+		_ = json.Unmarshal(rawBody, &data.Body)
+	}
+
+	return data, nil
+}
+
+// compileTemplates parses the route's templates so that errors surface when
+// the routes are loaded rather than on the first request.
+func (route *Route) compileTemplates() error {
+	if route.BodyTemplate != "" {
+		tmpl, err := parseTemplate("body", route.BodyTemplate)
+		if err != nil {
+			return err
+		}
+		route.bodyTemplate = tmpl
+	}
+
+	if len(route.HeaderTemplates) > 0 {
+		route.headerTemplates = make(map[string]*template.Template, len(route.HeaderTemplates))
+		for name, text := range route.HeaderTemplates {
+			tmpl, err := parseTemplate(name, text)
+			if err != nil {
+				return err
+			}
+			route.headerTemplates[name] = tmpl
+		}
+	}
+
+	return nil
+}
+
+// renderTemplates renders the route's header and body templates. Routes added
+// without going through validation are parsed on the fly.
+func (route *Route) renderTemplates(data *templateData) (map[string]string, string, error) {
+	headers := make(map[string]string, len(route.HeaderTemplates))
+	for name, text := range route.HeaderTemplates {
+		tmpl := route.headerTemplates[name]
+		if tmpl == nil {
+			var err error
+			if tmpl, err = parseTemplate(name, text); err != nil {
+				return nil, "", err
+			}
+		}
+		value, err := executeTemplate(tmpl, data)
+		if err != nil {
+			return nil, "", err
+		}
+		headers[name] = value
+	}
+
+	if route.BodyTemplate == "" {
+		return headers, "", nil
+	}
+	tmpl := route.bodyTemplate
+	if tmpl == nil {
+		var err error
+		if tmpl, err = parseTemplate("body", route.BodyTemplate); err != nil {
+			return nil, "", err
+		}
+	}
+	body, err := executeTemplate(tmpl, data)
+	return headers, body, err
+}
+
+func executeTemplate(tmpl *template.Template, data *templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
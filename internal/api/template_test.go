@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestServeHTTP_Template(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[
+		{
+			"path": "/users/{id}",
+			"method": "POST",
+			"status_code": 200,
+			"body_template": "{\"id\":\"{{.Params.id}}\",\"name\":\"{{.Body.name}}\",\"q\":\"{{.Query.Get \"q\"}}\",\"session\":\"{{.Cookies.session}}\"}",
+			"header_templates": {
+				"X-Request-Id": "{{.Headers.Get \"X-Request-Id\"}}",
+				"X-Trace": "{{uuid}}"
+			}
+		}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to load routes: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/users/42?q=search", strings.NewReader(`{"name":"faux"}`))
+	req.Header.Set("X-Request-Id", "abc-123")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	expected := `{"id":"42","name":"faux","q":"search","session":"s1"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+	if ctype := rr.Header().Get("Content-Type"); ctype != "application/json" {
+		t.Errorf("Content type header does not match: got %v want %v", ctype, "application/json")
+	}
+	if id := rr.Header().Get("X-Request-Id"); id != "abc-123" {
+		t.Errorf("X-Request-Id header does not match: got %v want %v", id, "abc-123")
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if trace := rr.Header().Get("X-Trace"); !uuidPattern.MatchString(trace) {
+		t.Errorf("X-Trace header is not a UUID: got %v", trace)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	route := &Route{BodyTemplate: `{{base64 "faux"}} {{base64Decode "ZmF1eA=="}} {{randInt 5 5}} {{now "2006" | len}}`}
+
+	_, body, err := route.renderTemplates(&templateData{})
+	if err != nil {
+		t.Fatalf("Failed to render template: %v", err)
+	}
+	if body != "ZmF1eA== faux 5 4" {
+		t.Errorf("Unexpected template output: got %v", body)
+	}
+}
+
+func TestLoadRoutesFromJSON_InvalidTemplate(t *testing.T) {
+	router := NewRouter()
+	err := router.LoadRoutesFromJSON([]byte(`[{"path": "/t", "method": "GET", "status_code": 200, "body_template": "{{.Params.id"}]`))
+	if err == nil || !strings.Contains(err.Error(), "GET /t") {
+		t.Errorf("Expected template error naming the route, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxNearMisses caps how many non-matching requests a failed verification
+// reports.
+const maxNearMisses = 5
+
+// VerifyRequest describes which recorded requests to count and how many there
+// should be. At most one of Exactly, AtLeast and AtMost should be set; with
+// none, at least one request is expected.
+type VerifyRequest struct {
+	Method string `json:"method,omitempty"`
+	// Path is a route path pattern, so /users/{id} and /users/** work.
+	Path string `json:"path,omitempty"`
+	RequestMatch
+	Exactly *int `json:"exactly,omitempty"`
+	AtLeast *int `json:"at_least,omitempty"`
+	AtMost  *int `json:"at_most,omitempty"`
+}
+
+// VerifyResult is the outcome of a verification.
+type VerifyResult struct {
+	Passed   bool   `json:"passed"`
+	Count    int    `json:"count"`
+	Expected string `json:"expected"`
+	// NearMisses lists the recorded requests that came closest to matching,
+	// and only when the verification failed.
+	NearMisses []NearMiss `json:"near_misses,omitempty"`
+}
+
+// NearMiss is a recorded request that failed to match, and why.
+type NearMiss struct {
+	Request  JournalEntry `json:"request"`
+	Failures []string     `json:"failures"`
+}
+
+// Verify counts the recorded requests that match v and checks the count.
+func (j *Journal) Verify(v *VerifyRequest) VerifyResult {
+	var (
+		result VerifyResult
+		misses []NearMiss
+	)
+	for _, entry := range j.Entries() {
+		failures := v.check(&entry)
+		if len(failures) == 0 {
+			result.Count++
+		} else {
+			misses = append(misses, NearMiss{Request: entry, Failures: failures})
+		}
+	}
+
+	result.Passed, result.Expected = v.expect(result.Count)
+	if !result.Passed {
+		// Fewest failures first; among equals, the most recent first.
+		sort.SliceStable(misses, func(a, b int) bool {
+			if len(misses[a].Failures) != len(misses[b].Failures) {
+				return len(misses[a].Failures) < len(misses[b].Failures)
+			}
+			return misses[a].Request.Time.After(misses[b].Request.Time)
+		})
+		if len(misses) > maxNearMisses {
+			misses = misses[:maxNearMisses]
+		}
+		result.NearMisses = misses
+	}
+	return result
+}
+
+// check returns why the entry does not match, or nil if it does.
+func (v *VerifyRequest) check(entry *JournalEntry) []string {
+	var failures []string
+	if v.Method != "" && !strings.EqualFold(v.Method, entry.Method) {
+		failures = append(failures, fmt.Sprintf("method is %s, not %s", entry.Method, strings.ToUpper(v.Method)))
+	}
+	if v.Path != "" {
+		if _, ok := matchPath(v.Path, entry.Path); !ok {
+			failures = append(failures, fmt.Sprintf("path %s does not match %s", entry.Path, v.Path))
+		}
+	}
+
+	var query url.Values
+	if parsed, err := url.Parse(entry.URL); err == nil {
+		query = parsed.Query()
+	}
+	return append(failures, v.RequestMatch.check(query, entry.Headers, []byte(entry.Body))...)
+}
+
+// expect reports whether count meets the expectation, and describes it.
+func (v *VerifyRequest) expect(count int) (bool, string) {
+	switch {
+	case v.Exactly != nil:
+		return count == *v.Exactly, fmt.Sprintf("exactly %d", *v.Exactly)
+	case v.AtLeast != nil && v.AtMost != nil:
+		return count >= *v.AtLeast && count <= *v.AtMost, fmt.Sprintf("between %d and %d", *v.AtLeast, *v.AtMost)
+	case v.AtMost != nil:
+		return count <= *v.AtMost, fmt.Sprintf("at most %d", *v.AtMost)
+	case v.AtLeast != nil:
+		return count >= *v.AtLeast, fmt.Sprintf("at least %d", *v.AtLeast)
+	default:
+		return count >= 1, "at least 1"
+	}
+}
+
+// serveVerify answers 200 when the verification passes and 417 when it
+// fails, so scripts can rely on the status code alone.
+func (r *Router) serveVerify(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var v VerifyRequest
+	if err := json.NewDecoder(req.Body).Decode(&v); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if err := v.RequestMatch.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := r.Journal.Verify(&v)
+	status := http.StatusOK
+	if !result.Passed {
+		status = http.StatusExpectationFailed
+	}
+	writeJSON(w, status, result)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminVerify(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(&Route{Path: "/orders", Method: "POST", StatusCode: http.StatusCreated})
+
+	for _, item := range []string{"book", "book", "pen"} {
+		req := httptest.NewRequest("POST", "/orders", strings.NewReader(`{"item": "`+item+`"}`))
+		req.Header.Set("X-Tenant", "a")
+		serveAndRecord(router, req)
+	}
+	serveAndRecord(router, httptest.NewRequest("GET", "/orders", http.NoBody))
+
+	testCases := []struct {
+		desc           string
+		body           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			desc:           "Exactly",
+			body:           `{"method": "POST", "path": "/orders", "body": {"$.item": "book"}, "exactly": 2}`,
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			desc:           "At least by default",
+			body:           `{"path": "/orders", "headers": {"X-Tenant": {"equals": "a"}}}`,
+			expectedStatus: http.StatusOK,
+			expectedCount:  3,
+		},
+		{
+			desc:           "At most fails",
+			body:           `{"method": "POST", "path": "/orders", "at_most": 1}`,
+			expectedStatus: http.StatusExpectationFailed,
+			expectedCount:  3,
+		},
+		{
+			desc:           "Never called",
+			body:           `{"method": "DELETE", "path": "/orders/{id}", "at_least": 1}`,
+			expectedStatus: http.StatusExpectationFailed,
+			expectedCount:  0,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rr := adminRequest(t, router, "POST", AdminPrefix+"verify", tC.body)
+			if rr.Code != tC.expectedStatus {
+				t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, tC.expectedStatus)
+			}
+
+			var result VerifyResult
+			if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to decode result: %v", err)
+			}
+			if result.Count != tC.expectedCount {
+				t.Errorf("Expected count %d, got %d", tC.expectedCount, result.Count)
+			}
+			if result.Passed != (tC.expectedStatus == http.StatusOK) {
+				t.Errorf("Unexpected passed value: %+v", result)
+			}
+		})
+	}
+}
+
+func TestJournalVerify_NearMisses(t *testing.T) {
+	router := NewRouter()
+	serveAndRecord(router, httptest.NewRequest("GET", "/users/1", http.NoBody))
+	req := httptest.NewRequest("POST", "/users/", strings.NewReader(`{"name": "bob"}`))
+	req.Header.Set("X-Tenant", "a")
+	serveAndRecord(router, req)
+
+	result := router.Journal.Verify(&VerifyRequest{
+		Method: "POST",
+		Path:   "/users/{id}",
+		RequestMatch: RequestMatch{
+			Headers: map[string]ValueMatch{"X-Tenant": {Present: true}},
+			Body:    map[string]interface{}{"$.name": "alice"},
+		},
+	})
+
+	if result.Passed || len(result.NearMisses) != 2 {
+		t.Fatalf("Expected a failure with two near misses, got %+v", result)
+	}
+
+	closest := result.NearMisses[0]
+	if closest.Request.Method != "POST" || len(closest.Failures) != 2 {
+		t.Errorf("Expected the POST to be closest, got %+v", closest)
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// webSocketGUID is appended to the client's key to compute the handshake
+// answer, as set out in RFC 6455.
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketFrame caps the frames read from clients.
+const maxWebSocketFrame = 1 << 20
+
+// WebSocket frame opcodes.
+const (
+	wsText   = 0x1
+	wsBinary = 0x2
+	wsClose  = 0x8
+	wsPing   = 0x9
+	wsPong   = 0xA
+)
+
+// webSocketMock is a WebSocket endpoint that sends its messages to every
+// client once it connects, then reads and discards whatever the client sends
+// until it closes the connection.
+type webSocketMock struct {
+	messages [][]byte
+}
+
+// isWebSocketUpgrade reports whether req is a WebSocket handshake.
+func isWebSocketUpgrade(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		headerHasToken(req.Header, "Connection", "upgrade") &&
+		req.Header.Get("Sec-WebSocket-Key") != ""
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serve completes the handshake and runs the connection until the client
+// closes it.
+func (m *webSocketMock) serve(w http.ResponseWriter, req *http.Request) {
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket connections are not supported here", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(req.Header.Get("Sec-WebSocket-Key") + webSocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	for _, message := range m.messages {
+		opcode := byte(wsText)
+		if !utf8.Valid(message) {
+			opcode = wsBinary
+		}
+		writeWebSocketFrame(rw.Writer, opcode, message)
+	}
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	for {
+		opcode, payload, err := readWebSocketFrame(rw.Reader)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsClose:
+			writeWebSocketFrame(rw.Writer, wsClose, payload)
+			_ = rw.Flush()
+			return
+		case wsPing:
+			writeWebSocketFrame(rw.Writer, wsPong, payload)
+			if err := rw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeWebSocketFrame writes an unmasked, unfragmented frame, as servers send
+// them.
+func writeWebSocketFrame(w *bufio.Writer, opcode byte, payload []byte) {
+	_ = w.WriteByte(0x80 | opcode)
+	switch length := len(payload); {
+	case length < 126:
+		_ = w.WriteByte(byte(length))
+	case length <= 0xFFFF:
+		_ = w.WriteByte(126)
+		_ = binary.Write(w, binary.BigEndian, uint16(length))
+	default:
+		_ = w.WriteByte(127)
+		_ = binary.Write(w, binary.BigEndian, uint64(length))
+	}
+	_, _ = w.Write(payload)
+}
+
+// readWebSocketFrame reads one frame and unmasks its payload.
+func readWebSocketFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended uint16
+		if err := binary.Read(r, binary.BigEndian, &extended); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(extended)
+	case 127:
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return 0, nil, err
+		}
+	}
+	if length > maxWebSocketFrame {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
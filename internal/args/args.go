@@ -2,7 +2,10 @@ package args
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
@@ -16,6 +19,143 @@ type AppConfig struct {
 	Host       string `yaml:"host"`
 	Port       int    `yaml:"port"`
 	QuietStart bool
+	// JournalSize is how many recent requests are kept for /__faux/requests.
+	JournalSize int `yaml:"journalSize"`
+	// PlainNotFound drops the list of closest routes from 404 responses.
+	PlainNotFound bool `yaml:"plainNotFound"`
+	// ProxyTo is the upstream base URL unmatched requests are forwarded to.
+	ProxyTo          string            `yaml:"proxyTo"`
+	ProxyRewriteHost bool              `yaml:"proxyRewriteHost"`
+	ProxyHeaders     map[string]string `yaml:"proxyHeaders"`
+	// ProxyTimeout is in milliseconds; zero means no limit.
+	ProxyTimeout int `yaml:"proxyTimeout"`
+	// OpenAPI lists specs to serve routes from, as file paths, URLs or
+	// inline documents. Routes files win over them for the same operation.
+	OpenAPI stringList `yaml:"openapi"`
+	// OpenAPIValidation is enforce, log or off: whether requests to routes
+	// from a spec are rejected, logged or let through when they break it.
+	OpenAPIValidation string `yaml:"openapiValidation"`
+	// JWTSecrets and JWTJWKS are the keys JWTs are verified with on routes
+	// with auth_required, and on routes whose JWT auth names no keys.
+	JWTSecrets  stringList `yaml:"jwtSecrets"`
+	JWTJWKS     string     `yaml:"jwtJwks"`
+	JWTIssuer   string     `yaml:"jwtIssuer"`
+	JWTAudience string     `yaml:"jwtAudience"`
+}
+
+// stringList is a repeatable flag, and in YAML either a single string or a
+// list of them.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// headerFlag collects repeated "Name: Value" flags into a map.
+type headerFlag struct {
+	headers *map[string]string
+}
+
+func (f headerFlag) String() string {
+	if f.headers == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f.headers))
+	for name, value := range *f.headers {
+		pairs = append(pairs, name+": "+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (f headerFlag) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected Name: Value, got %q", value)
+	}
+	if *f.headers == nil {
+		*f.headers = make(map[string]string)
+	}
+	(*f.headers)[strings.TrimSpace(name)] = strings.TrimSpace(headerValue)
+	return nil
+}
+
+// LintConfig holds the options of the lint subcommand.
+type LintConfig struct {
+	Routes string
+	Spec   string
+	JSON   bool
+}
+
+// ParseLintInput parses the arguments that follow "faux lint".
+func ParseLintInput(arguments []string, config *LintConfig) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.StringVar(&config.Routes, "routes", "", "Routes file or directory to check")
+	flags.StringVar(&config.Spec, "spec", "", "OpenAPI spec to check the routes against, as a file or URL")
+	flags.BoolVar(&config.JSON, "json", false, "Print the findings as JSON")
+
+	if err := flags.Parse(arguments); err != nil {
+		return err
+	}
+	if config.Routes == "" || config.Spec == "" {
+		return fmt.Errorf("lint needs -routes and -spec")
+	}
+	return nil
+}
+
+// RecordConfig holds the options of the record subcommand.
+type RecordConfig struct {
+	Target     string
+	Out        string
+	Host       string
+	Port       int
+	ReplayOnly bool
+	// Timeout is in milliseconds; zero means no limit.
+	Timeout int
+}
+
+// ParseRecordInput parses the arguments that follow "faux record".
+func ParseRecordInput(arguments []string, config *RecordConfig) error {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	flags.StringVar(&config.Target, "target", "", "Upstream base URL to record")
+	flags.StringVar(&config.Out, "out", "", "Directory to write recorded routes to")
+	flags.StringVar(&config.Host, "host", "localhost", "Application host")
+	flags.IntVar(&config.Port, "port", 8080, "Application port")
+	flags.BoolVar(&config.ReplayOnly, "replay-only", false, "Serve recorded routes without forwarding anything")
+	flags.IntVar(&config.Timeout, "timeout", 30000, "Timeout for forwarded requests in milliseconds, 0 for none")
+
+	if err := flags.Parse(arguments); err != nil {
+		return err
+	}
+	if config.Out == "" {
+		return fmt.Errorf("record needs -out")
+	}
+	if config.Target == "" && !config.ReplayOnly {
+		return fmt.Errorf("record needs -target unless -replay-only is set")
+	}
+	return nil
 }
 
 func NewAppConfig() *AppConfig {
@@ -31,6 +171,18 @@ func ParseInput(appConfig *AppConfig) error {
 	flag.StringVar(&appConfig.Host, "host", "localhost", "Application host")
 	flag.IntVar(&appConfig.Port, "port", 8080, "Application port")
 	flag.BoolVar(&appConfig.QuietStart, "quiet-start", false, "Mute any welcome messages")
+	flag.BoolVar(&appConfig.PlainNotFound, "plain-404", false, "Send plain 404 responses without the closest routes")
+	flag.StringVar(&appConfig.ProxyTo, "proxy-to", "", "Upstream base URL to forward unmatched requests to")
+	flag.BoolVar(&appConfig.ProxyRewriteHost, "proxy-rewrite-host", false, "Send the upstream host in the Host header of forwarded requests")
+	flag.Var(headerFlag{&appConfig.ProxyHeaders}, "proxy-header", "Header to add to forwarded requests as \"Name: Value\" (repeatable)")
+	flag.IntVar(&appConfig.ProxyTimeout, "proxy-timeout", 30000, "Timeout for forwarded requests in milliseconds, 0 for none")
+	flag.Var(&appConfig.OpenAPI, "openapi", "OpenAPI spec to serve routes from, as a file, URL or inline document (repeatable)")
+	flag.StringVar(&appConfig.OpenAPIValidation, "openapi-validation", "enforce", "Check requests to OpenAPI routes against the spec: enforce, log or off")
+	flag.Var(&appConfig.JWTSecrets, "jwt-secret", "Secret to verify HS256 JWTs with (repeatable)")
+	flag.StringVar(&appConfig.JWTJWKS, "jwt-jwks", "", "Local JWKS file to verify JWTs with")
+	flag.StringVar(&appConfig.JWTIssuer, "jwt-issuer", "", "Issuer JWTs must have")
+	flag.StringVar(&appConfig.JWTAudience, "jwt-audience", "", "Audience JWTs must have")
+	flag.IntVar(&appConfig.JournalSize, "journal-size", 1000, "Number of recent requests to keep in the request journal")
 
 	flag.Parse()
 
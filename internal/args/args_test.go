@@ -34,6 +34,23 @@ port: 8080`,
 			},
 			expectError: false,
 		},
+		{
+			name: "OpenAPI spec list",
+			configYaml: `
+openapi:
+  - api.yaml
+  - https://example.com/spec.json`,
+			expected: &AppConfig{
+				OpenAPI: stringList{"api.yaml", "https://example.com/spec.json"},
+			},
+		},
+		{
+			name:       "Single OpenAPI spec",
+			configYaml: `openapi: api.yaml`,
+			expected: &AppConfig{
+				OpenAPI: stringList{"api.yaml"},
+			},
+		},
 		{
 			name:        "Invalid YAML",
 			configYaml:  `invalid_yaml`,
@@ -79,6 +96,18 @@ port: 8080`,
 	}
 }
 
+func TestHeaderFlag(t *testing.T) {
+	var headers map[string]string
+	f := headerFlag{&headers}
+
+	assert.NoError(t, f.Set("X-Api-Key: secret"))
+	assert.NoError(t, f.Set("X-Env:staging"))
+	assert.Error(t, f.Set("no-colon"))
+
+	assert.Equal(t, map[string]string{"X-Api-Key": "secret", "X-Env": "staging"}, headers)
+	assert.Equal(t, "X-Api-Key: secret, X-Env: staging", f.String())
+}
+
 func TestParseInput(t *testing.T) {
 	// Define test cases
 	testCases := []struct {
@@ -91,14 +120,20 @@ func TestParseInput(t *testing.T) {
 	}{
 		{
 			name: "CLI Flags",
-			args: []string{"-token=s3cr3tt0k3n", "-routes=/path/to/routes.json", "-colorize=true", "-log-format='{{.Time}} {{.Method}} {{.StatusCode}} {{.Path}} {{.ResponseTime}}'", "-host=localhost", "-port=8080"},
+			args: []string{"-token=s3cr3tt0k3n", "-routes=/path/to/routes.json", "-colorize=true", "-log-format='{{.Time}} {{.Method}} {{.StatusCode}} {{.Path}} {{.ResponseTime}}'", "-host=localhost", "-port=8080", "-openapi=api.yaml", "-openapi=https://example.com/spec.json", "-jwt-secret=k1", "-jwt-secret=k2", "-jwt-issuer=https://issuer.example.com"},
 			expected: &AppConfig{
-				AuthToken:  "s3cr3tt0k3n",
-				RoutesPath: "/path/to/routes.json",
-				Colorize:   true,
-				LogFormat:  "'{{.Time}} {{.Method}} {{.StatusCode}} {{.Path}} {{.ResponseTime}}'",
-				Host:       "localhost",
-				Port:       8080,
+				AuthToken:         "s3cr3tt0k3n",
+				RoutesPath:        "/path/to/routes.json",
+				Colorize:          true,
+				LogFormat:         "'{{.Time}} {{.Method}} {{.StatusCode}} {{.Path}} {{.ResponseTime}}'",
+				Host:              "localhost",
+				Port:              8080,
+				JournalSize:       1000,
+				ProxyTimeout:      30000,
+				OpenAPI:           stringList{"api.yaml", "https://example.com/spec.json"},
+				OpenAPIValidation: "enforce",
+				JWTSecrets:        stringList{"k1", "k2"},
+				JWTIssuer:         "https://issuer.example.com",
 			},
 			expectParseError: false,
 		},
@@ -129,3 +164,35 @@ func TestParseInput(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRecordInput(t *testing.T) {
+	config := &RecordConfig{}
+	err := ParseRecordInput([]string{"-target", "https://api.example.com", "-out", "fixtures", "-port", "9000"}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, &RecordConfig{
+		Target:  "https://api.example.com",
+		Out:     "fixtures",
+		Host:    "localhost",
+		Port:    9000,
+		Timeout: 30000,
+	}, config)
+
+	err = ParseRecordInput([]string{"-out", "fixtures", "-replay-only"}, &RecordConfig{})
+	assert.NoError(t, err)
+
+	err = ParseRecordInput([]string{"-out", "fixtures"}, &RecordConfig{})
+	assert.Error(t, err)
+
+	err = ParseRecordInput([]string{"-target", "https://api.example.com"}, &RecordConfig{})
+	assert.Error(t, err)
+}
+
+func TestParseLintInput(t *testing.T) {
+	config := &LintConfig{}
+	err := ParseLintInput([]string{"-routes", "routes", "-spec", "api.yaml", "-json"}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, &LintConfig{Routes: "routes", Spec: "api.yaml", JSON: true}, config)
+
+	err = ParseLintInput([]string{"-routes", "routes"}, &LintConfig{})
+	assert.Error(t, err)
+}